@@ -1,4 +1,4 @@
 Set-Location $PSScriptRoot
-go build
+go build -o protoc-gen-json.exe ./cmd/protoc-gen-json
 protoc --plugin="protoc-gen-json=${PSScriptRoot}/protoc-gen-json.exe" --json_out="./" --json_opt="test.json" ./test.proto
 
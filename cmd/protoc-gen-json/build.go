@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// runBuild implements the `build` subcommand, which runs the plugin on a `FileDescriptorSet` instead of a request
+// from `protoc`, EG the output of `protoc --descriptor_set_out` or `buf build`
+func runBuild(args []string) error {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	descriptorSet := flags.String("descriptor-set", "", "FileDescriptorSet to read, EG from protoc --descriptor_set_out or buf build -o (required)")
+	out := flags.String("out", "output.json", "file to write the output to; other outputs are written next to it")
+	opt := flags.String("opt", "", "plugin parameters, as passed to --json_opt, EG format=yaml,lint=all")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: protoc-gen-json build -descriptor-set=FILE [flags] [FILE.proto...]")
+		fmt.Fprintln(flags.Output(), "Generates the given files, or by default the files the set was built for, without their imports.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*descriptorSet) == 0 {
+		flags.Usage()
+		return fmt.Errorf("-descriptor-set is required")
+	}
+
+	data, err := os.ReadFile(*descriptorSet)
+	if err != nil {
+		return err
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(data, set); err != nil {
+		return fmt.Errorf("%s: not a FileDescriptorSet: %w", *descriptorSet, err)
+	}
+
+	req, err := buildRequest(set, flags.Args(), *opt, filepath.Base(*out))
+	if err != nil {
+		return err
+	}
+
+	resp, err := protojson.Generate(req, warn)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s", resp.GetError())
+	}
+
+	// Outputs are named relative to the output directory, as with `protoc`
+	dir := filepath.Dir(*out)
+	for _, file := range resp.File {
+		path := filepath.Join(dir, filepath.FromSlash(file.GetName()))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(file.GetContent()), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildRequest builds the request `protoc` would send for the files `generate` in `set` (see `defaultFilesToGenerate`
+// if there are none).
+// The output filename is passed as the `out` parameter, ahead of `opt` so an explicit `out` in `opt` still wins.
+func buildRequest(set *descriptorpb.FileDescriptorSet, generate []string, opt string, output string) (*pluginpb.CodeGeneratorRequest, error) {
+	names := make(map[string]bool)
+	for _, file := range set.GetFile() {
+		names[file.GetName()] = true
+	}
+
+	// protoc leaves imports out of descriptor sets unless asked, and the parser needs them all
+	for _, file := range set.GetFile() {
+		for _, dependency := range file.GetDependency() {
+			if !names[dependency] {
+				return nil, fmt.Errorf("%s imports %s, which isn't in the descriptor set; rebuild it with --include_imports", file.GetName(), dependency)
+			}
+		}
+	}
+
+	if len(generate) == 0 {
+		generate = defaultFilesToGenerate(set)
+	}
+	for _, name := range generate {
+		if !names[name] {
+			return nil, fmt.Errorf("%s is not in the descriptor set", name)
+		}
+	}
+
+	params := []string{"out=" + output}
+	if len(strings.TrimSpace(opt)) > 0 {
+		params = append(params, opt)
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: generate,
+		Parameter:      proto.String(strings.Join(params, ",")),
+		ProtoFile:      set.GetFile(),
+	}, nil
+}
+
+// bufImageFileExtension is the field of the files of a `buf` image which holds buf's own metadata about them
+const bufImageFileExtension = 8042
+
+// defaultFilesToGenerate returns the files of `set` which were named when it was built, as `protoc` would generate
+// them. `buf` marks the files which are only in an image as imports; `protoc` doesn't record which files were named,
+// so for its sets, every file which no other file in the set imports is generated.
+func defaultFilesToGenerate(set *descriptorpb.FileDescriptorSet) []string {
+	marked := false
+	imported := make(map[string]bool)
+	for _, file := range set.GetFile() {
+		if _, found := bufImport(file); found {
+			marked = true
+		}
+		for _, dependency := range file.GetDependency() {
+			imported[dependency] = true
+		}
+	}
+
+	ret := make([]string, 0)
+	for _, file := range set.GetFile() {
+		isImport, _ := bufImport(file)
+		if !marked {
+			isImport = imported[file.GetName()]
+		}
+		if !isImport {
+			ret = append(ret, file.GetName())
+		}
+	}
+	return ret
+}
+
+// bufImport returns whether `buf` marked a file of an image as an import, if it marked it either way
+func bufImport(file *descriptorpb.FileDescriptorProto) (isImport bool, found bool) {
+	raw := file.ProtoReflect().GetUnknown()
+	for len(raw) > 0 {
+		number, wireType, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return false, false
+		}
+		raw = raw[n:]
+
+		if number == bufImageFileExtension && wireType == protowire.BytesType {
+			extension, n := protowire.ConsumeBytes(raw)
+			if n < 0 {
+				return false, false
+			}
+			return bufExtensionIsImport(extension), true
+		}
+
+		n = protowire.ConsumeFieldValue(number, wireType, raw)
+		if n < 0 {
+			return false, false
+		}
+		raw = raw[n:]
+	}
+	return false, false
+}
+
+// bufExtensionIsImport decodes the `is_import` field of buf's `ImageFileExtension`
+func bufExtensionIsImport(extension []byte) bool {
+	isImport := false
+	for len(extension) > 0 {
+		number, wireType, n := protowire.ConsumeTag(extension)
+		if n < 0 {
+			return isImport
+		}
+		extension = extension[n:]
+
+		if number == 1 && wireType == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(extension)
+			if n < 0 {
+				return isImport
+			}
+			isImport = value != 0
+			extension = extension[n:]
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(number, wireType, extension)
+		if n < 0 {
+			return isImport
+		}
+		extension = extension[n:]
+	}
+	return isImport
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testFile describes a file of a descriptor set, with the files it imports
+func testFile(name string, dependencies ...string) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{Name: proto.String(name), Dependency: dependencies}
+}
+
+// markBufImport marks a file as `buf` does in images, as an import or not
+func markBufImport(file *descriptorpb.FileDescriptorProto, isImport bool) *descriptorpb.FileDescriptorProto {
+	value := uint64(0)
+	if isImport {
+		value = 1
+	}
+	extension := protowire.AppendTag(nil, 1, protowire.VarintType)
+	extension = protowire.AppendVarint(extension, value)
+
+	raw := protowire.AppendTag(nil, bufImageFileExtension, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, extension)
+	file.ProtoReflect().SetUnknown(raw)
+	return file
+}
+
+func TestBuildRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []*descriptorpb.FileDescriptorProto
+		generate []string
+		want     []string
+		err      string
+	}{
+		{
+			name: "protoc set",
+			files: []*descriptorpb.FileDescriptorProto{
+				testFile("google/protobuf/timestamp.proto"),
+				testFile("my/types.proto", "google/protobuf/timestamp.proto"),
+				testFile("my/api.proto", "my/types.proto"),
+				testFile("my/other.proto"),
+			},
+			want: []string{"my/api.proto", "my/other.proto"},
+		},
+		{
+			name: "buf image",
+			files: []*descriptorpb.FileDescriptorProto{
+				markBufImport(testFile("google/protobuf/timestamp.proto"), true),
+				markBufImport(testFile("my/types.proto", "google/protobuf/timestamp.proto"), false),
+				markBufImport(testFile("my/api.proto", "my/types.proto"), false),
+			},
+			want: []string{"my/types.proto", "my/api.proto"},
+		},
+		{
+			name: "listed files",
+			files: []*descriptorpb.FileDescriptorProto{
+				testFile("google/protobuf/timestamp.proto"),
+				testFile("my/types.proto", "google/protobuf/timestamp.proto"),
+			},
+			generate: []string{"google/protobuf/timestamp.proto"},
+			want:     []string{"google/protobuf/timestamp.proto"},
+		},
+		{
+			name:     "unknown file",
+			files:    []*descriptorpb.FileDescriptorProto{testFile("my/api.proto")},
+			generate: []string{"my/missing.proto"},
+			err:      "my/missing.proto is not in the descriptor set",
+		},
+		{
+			name:  "missing import",
+			files: []*descriptorpb.FileDescriptorProto{testFile("my/api.proto", "my/types.proto")},
+			err:   "rebuild it with --include_imports",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set := &descriptorpb.FileDescriptorSet{File: test.files}
+			req, err := buildRequest(set, test.generate, "lint=all", "api.json")
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(req.GetFileToGenerate(), test.want) {
+				t.Errorf("got files %q, want %q", req.GetFileToGenerate(), test.want)
+			}
+			if req.GetParameter() != "out=api.json,lint=all" {
+				t.Errorf("got parameter %q", req.GetParameter())
+			}
+			if len(req.GetProtoFile()) != len(test.files) {
+				t.Errorf("got %d files in the request, want all %d", len(req.GetProtoFile()), len(test.files))
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// runDiff implements the `diff` subcommand, which prints the semantic diff between two outputs
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	out := flags.String("out", "", "file to write the diff to (default stdout)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: protoc-gen-json diff [flags] OLD.json NEW.json")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected 2 outputs to compare, got %d", flags.NArg())
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown diff format %q", *format)
+	}
+
+	before, err := protojson.ReadContextFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := protojson.ReadContextFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff, err := protojson.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	diff.Old, diff.New = flags.Arg(0), flags.Arg(1)
+
+	w := io.Writer(os.Stdout)
+	if len(*out) > 0 {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	return diff.WriteText(w)
+}
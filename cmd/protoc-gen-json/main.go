@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pseudomuto/protokit"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// Required struct for protokit -- its `Generate()` method will be called.
+type plugin struct{}
+
+// Generate generates output from incoming proto files
+func (p *plugin) Generate(req *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	return protojson.Generate(req, warn)
+}
+
+// warn prints a diagnostic of the library to stderr, which `protoc` shows to the user
+func warn(message string) {
+	fmt.Fprintf(os.Stderr, "protoc-gen-json: %s\n", message)
+}
+
+// subcommands can be run directly, EG `protoc-gen-json diff old.json new.json`
+var subcommands = map[string]func(args []string) error{
+	"build": runBuild,
+	"diff":  runDiff,
+	"query": runQuery,
+	"serve": runServe,
+}
+
+// Entry point of plugin -- takes input from `protoc` and handles it
+func main() {
+	// `protoc` runs plugins without arguments, so any argument selects a subcommand instead
+	if len(os.Args) > 1 {
+		run, found := subcommands[os.Args[1]]
+		if !found {
+			fmt.Fprintf(os.Stderr, "protoc-gen-json: unknown subcommand %q\n", os.Args[1])
+			os.Exit(2)
+		}
+
+		if err := run(os.Args[2:]); err != nil {
+			// Flag sets print their own usage when asked for help
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "protoc-gen-json %s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run plugin via `protokit`
+	if err := protokit.RunPlugin(new(plugin)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// queryCommands are the commands of the `query` subcommand
+var queryCommands = map[string]func(q *querier, args []string) error{
+	"describe": (*querier).describe,
+	"refs":     (*querier).refs,
+	"list":     (*querier).list,
+	"find":     (*querier).find,
+}
+
+// queryKinds maps the kinds accepted by `query list` to the types of index entries
+var queryKinds = map[string]string{
+	"services":    "service",
+	"methods":     "method",
+	"messages":    "message",
+	"fields":      "field",
+	"enums":       "enum",
+	"enum_values": "enum_value",
+}
+
+// querier answers queries about an output, writing tables or JSON
+type querier struct {
+	r      *protojson.Reader
+	w      io.Writer
+	asJSON bool
+}
+
+// queryRow is an object in the result of a query
+type queryRow struct {
+	Kind        string      `json:"kind"`
+	FQN         string      `json:"fqn"`
+	File        string      `json:"file,omitempty"`
+	Detail      string      `json:"detail,omitempty"`
+	Value       interface{} `json:"value,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// runQuery implements the `query` subcommand, which answers questions about an output
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	input := flags.String("input", "output.json", "JSON output to query")
+	format := flags.String("format", "table", "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: protoc-gen-json query [flags] COMMAND [ARGS]")
+		fmt.Fprintln(flags.Output(), "commands:")
+		fmt.Fprintln(flags.Output(), "  describe NAME                    describe an object and what it declares")
+		fmt.Fprintln(flags.Output(), "  refs NAME                        list the fields and methods which refer to a message or enum")
+		fmt.Fprintln(flags.Output(), "  list KIND [-deprecated]          list the services, methods, messages, fields, enums or enum_values")
+		fmt.Fprintln(flags.Output(), "  find -option NAME[=VALUE]        list the objects which set an option")
+		fmt.Fprintln(flags.Output(), "NAME is an FQN, or the end of one if it's unambiguous, EG Book.title")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a command")
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown query format %q", *format)
+	}
+
+	command, found := queryCommands[flags.Arg(0)]
+	if !found {
+		return fmt.Errorf("unknown query command %q", flags.Arg(0))
+	}
+
+	r, err := protojson.OpenReader(*input)
+	if err != nil {
+		return err
+	}
+
+	q := &querier{r: r, w: os.Stdout, asJSON: *format == "json"}
+	if err := command(q, flags.Args()[1:]); err != nil {
+		return err
+	}
+	return r.Err()
+}
+
+// resolveName finds the FQN of the object `name`, which may be an FQN or its end, EG `Book.title`
+func (q *querier) resolveName(name string) (string, error) {
+	name = protojson.GetFQN(name)
+	if _, found := q.r.Index[name]; found {
+		return name, nil
+	}
+
+	matches := make([]string, 0)
+	for fqn := range q.r.Index {
+		if strings.HasSuffix(fqn, "."+name) {
+			matches = append(matches, fqn)
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no object named %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q is ambiguous: it could be %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// row describes an object as a row of a query result
+func (q *querier) row(fqn string) *queryRow {
+	ret := &queryRow{FQN: fqn}
+	if entry, found := q.r.Index[fqn]; found {
+		ret.Kind = entry.Type
+		ret.File = entry.File
+		ret.Description = entry.Description
+	}
+	if object, found := q.r.Lookup(fqn); found {
+		ret.Description = protojson.DescriptionOf(object)
+	}
+	return ret
+}
+
+// summary returns the first line of a description
+func summary(description string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	return line
+}
+
+// writeJSON writes a query result as indented JSON
+func (q *querier) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(q.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeRows writes a query result, as a table with the columns `columns` or as a JSON array
+func (q *querier) writeRows(rows []*queryRow, columns ...string) error {
+	if q.asJSON {
+		return q.writeJSON(rows)
+	}
+
+	w := tabwriter.NewWriter(q.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			switch column {
+			case "kind":
+				cells[i] = row.Kind
+			case "fqn":
+				cells[i] = row.FQN
+			case "file":
+				cells[i] = row.File
+			case "detail":
+				cells[i] = row.Detail
+			case "value":
+				cells[i] = fmt.Sprint(row.Value)
+			case "description":
+				cells[i] = summary(row.Description)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// describe describes an object, with the objects it declares
+func (q *querier) describe(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("describe takes 1 name, got %d", len(args))
+	}
+	fqn, err := q.resolveName(args[0])
+	if err != nil {
+		return err
+	}
+
+	object, found := q.r.Lookup(fqn)
+	entry := q.r.Index[fqn]
+	if !found {
+		// External types are only indexed
+		return q.writeRows([]*queryRow{q.row(fqn)}, "kind", "fqn", "file", "description")
+	}
+
+	// The object is described by its details, and the rows of what it declares or what uses it
+	details := make([][2]string, 0)
+	children := make([]*queryRow, 0)
+	switch object := object.(type) {
+	case *protojson.Service:
+		for _, method := range q.r.MethodsOf(object) {
+			row := q.row(method.FullName)
+			row.Detail = methodSignature(method)
+			children = append(children, row)
+		}
+	case *protojson.Method:
+		details = append(details, [2]string{"signature", methodSignature(object)})
+	case *protojson.Message:
+		for _, field := range q.r.FieldsOf(object) {
+			row := q.row(field.FullName)
+			row.Detail = fmt.Sprintf("%d %s", field.Number, fieldType(field))
+			children = append(children, row)
+		}
+		for _, method := range q.r.MethodsUsing(object) {
+			row := q.row(method.FullName)
+			row.Detail = methodSignature(method)
+			children = append(children, row)
+		}
+	case *protojson.Field:
+		details = append(details,
+			[2]string{"number", fmt.Sprint(object.Number)},
+			[2]string{"type", fieldType(object)},
+			[2]string{"json_name", object.JSONName},
+		)
+		if len(object.Oneof) > 0 {
+			details = append(details, [2]string{"oneof", object.Oneof})
+		}
+	case *protojson.Enum:
+		for _, value := range q.r.ValuesOf(object) {
+			row := q.row(value.FullName)
+			row.Detail = fmt.Sprint(value.Value)
+			children = append(children, row)
+		}
+	case *protojson.EnumValue:
+		details = append(details, [2]string{"value", fmt.Sprint(object.Value)})
+	}
+	options := protojson.OptionsOf(object)
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		details = append(details, [2]string{"option " + key, fmt.Sprint(options[key])})
+	}
+
+	if q.asJSON {
+		return q.writeJSON(&struct {
+			Kind     string      `json:"kind"`
+			FQN      string      `json:"fqn"`
+			File     string      `json:"file"`
+			Object   interface{} `json:"object"`
+			Children []*queryRow `json:"children"`
+		}{entry.Type, fqn, entry.File, object, children})
+	}
+
+	fmt.Fprintf(q.w, "%s %s (%s)\n", entry.Type, fqn, entry.File)
+	if description := strings.TrimSpace(protojson.DescriptionOf(object)); len(description) > 0 {
+		fmt.Fprintf(q.w, "\n%s\n", description)
+	}
+	if len(details) > 0 {
+		fmt.Fprintln(q.w)
+		w := tabwriter.NewWriter(q.w, 0, 0, 2, ' ', 0)
+		for _, detail := range details {
+			fmt.Fprintf(w, "%s:\t%s\n", detail[0], detail[1])
+		}
+		w.Flush()
+	}
+	if len(children) > 0 {
+		fmt.Fprintln(q.w)
+		return q.writeRows(children, "kind", "fqn", "detail", "description")
+	}
+	return nil
+}
+
+// methodSignature describes the request and response of a method, EG `(stream Request) returns (Response)`
+func methodSignature(method *protojson.Method) string {
+	input, output := method.InputType, method.OutputType
+	if method.ClientStreaming {
+		input = "stream " + input
+	}
+	if method.ServerStreaming {
+		output = "stream " + output
+	}
+	return fmt.Sprintf("(%s) returns (%s)", input, output)
+}
+
+// fieldType describes the type and cardinality of a field, EG `repeated my.pkg.Item`
+func fieldType(field *protojson.Field) string {
+	switch field.Label {
+	case "LABEL_REPEATED":
+		return "repeated " + field.FullType
+	case "LABEL_REQUIRED":
+		return "required " + field.FullType
+	default:
+		return field.FullType
+	}
+}
+
+// refs lists the fields and methods which refer to a message or enum
+func (q *querier) refs(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("refs takes 1 name, got %d", len(args))
+	}
+	fqn, err := q.resolveName(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, err := q.r.Context()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]*queryRow, 0)
+	for _, field := range ctx.Fields {
+		if field.FullType != fqn {
+			continue
+		}
+
+		// Map values are fields of synthetic entry messages, so refer to the map field itself instead
+		row := q.row(field.FullName)
+		row.Detail = fieldType(field)
+		if parent, found := q.r.Parent(field); found {
+			if entry, isMessage := parent.(*protojson.Message); isMessage && entry.IsMapEntry {
+				if mapField, found := q.r.Parent(entry); found {
+					for _, candidate := range q.r.FieldsOf(mapField.(*protojson.Message)) {
+						if candidate.FullType == entry.FullName {
+							row = q.row(candidate.FullName)
+							row.Detail = "map value"
+						}
+					}
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	for _, method := range ctx.Methods {
+		if method.InputType == fqn || method.OutputType == fqn {
+			row := q.row(method.FullName)
+			row.Detail = methodSignature(method)
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].FQN < rows[j].FQN })
+
+	return q.writeRows(rows, "kind", "fqn", "detail")
+}
+
+// list lists every object of a kind, optionally only the deprecated ones
+func (q *querier) list(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("list takes a kind: services, methods, messages, fields, enums or enum_values")
+	}
+	kind, found := queryKinds[args[0]]
+	if !found {
+		return fmt.Errorf("unknown kind %q: expected services, methods, messages, fields, enums or enum_values", args[0])
+	}
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	deprecated := flags.Bool("deprecated", false, "only list deprecated objects")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	rows := make([]*queryRow, 0)
+	for fqn, entry := range q.r.Index {
+		if entry.Type != kind || entry.External || q.isMapEntry(fqn) {
+			continue
+		}
+		if *deprecated {
+			object, _ := q.r.Lookup(fqn)
+			if isDeprecated, _ := protojson.OptionsOf(object)["deprecated"].(bool); !isDeprecated {
+				continue
+			}
+		}
+		rows = append(rows, q.row(fqn))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].FQN < rows[j].FQN })
+
+	return q.writeRows(rows, "fqn", "file", "description")
+}
+
+// isMapEntry reports whether `fqn` is a synthetic map entry message, or one of its fields
+func (q *querier) isMapEntry(fqn string) bool {
+	entry := q.r.Index[fqn]
+	if entry.Type == "field" {
+		fqn = entry.Parent
+	} else if entry.Type != "message" {
+		return false
+	}
+	message, found := q.r.Message(fqn)
+	return found && message.IsMapEntry
+}
+
+// find lists the objects which set an option, optionally to a value
+func (q *querier) find(args []string) error {
+	flags := flag.NewFlagSet("find", flag.ContinueOnError)
+	option := flags.String("option", "", "option to find, as NAME or NAME=VALUE, EG deprecated or my.pkg.auth=required; NAME may be the end of an FQN")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*option) == 0 {
+		return fmt.Errorf("find needs an -option")
+	}
+	name, value, hasValue := strings.Cut(*option, "=")
+	name = protojson.GetFQN(name)
+
+	ctx, err := q.r.Context()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]*queryRow, 0)
+	// Custom options are keyed by FQN, but can be found by the end of it, like objects
+	check := func(row func() *queryRow, options map[string]interface{}) {
+		for key, set := range options {
+			if key != name && !strings.HasSuffix(key, "."+name) {
+				continue
+			}
+			if hasValue && fmt.Sprint(set) != value {
+				continue
+			}
+			ret := row()
+			ret.Detail = key
+			ret.Value = set
+			rows = append(rows, ret)
+		}
+	}
+
+	for _, file := range ctx.Files {
+		file := file
+		check(func() *queryRow {
+			return &queryRow{Kind: "file", FQN: file.Name, File: file.Name, Description: file.Description}
+		}, file.Options)
+	}
+	for fqn := range q.r.Index {
+		if object, found := q.r.Lookup(fqn); found {
+			fqn := fqn
+			check(func() *queryRow { return q.row(fqn) }, protojson.OptionsOf(object))
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].FQN < rows[j].FQN })
+
+	return q.writeRows(rows, "kind", "fqn", "detail", "value")
+}
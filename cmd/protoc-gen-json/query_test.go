@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// fixturePackage is the package of the test fixtures of the library
+const fixturePackage = "com.pseudomuto.protokit.v1."
+
+// writeFixtureOutput generates the JSON output of the library's test fixtures, and returns the path it's written to
+func writeFixtureOutput(t *testing.T) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "pkg", "protojson", "testdata", "fileset.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(data, set); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := protojson.Generate(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"booking.proto", "extend.proto", "todo.proto", "todo_import.proto"},
+		Parameter:      proto.String(""),
+		ProtoFile:      set.GetFile(),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetError()) > 0 {
+		t.Fatal(resp.GetError())
+	}
+
+	path := filepath.Join(t.TempDir(), "output.json")
+	if err := os.WriteFile(path, []byte(resp.GetFile()[0].GetContent()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fixtureQuerier returns a querier of the output of the test fixtures, which writes JSON to `w`
+func fixtureQuerier(t *testing.T, w *bytes.Buffer) *querier {
+	t.Helper()
+
+	r, err := protojson.OpenReader(writeFixtureOutput(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &querier{r: r, w: w, asJSON: true}
+}
+
+func TestResolveName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  string
+	}{
+		{name: fixturePackage + "List.id", want: fixturePackage + "List.id"},
+		{name: "." + fixturePackage + "List", want: fixturePackage + "List"},
+		{name: "List.id", want: fixturePackage + "List.id"},
+		{name: "ListType", want: fixturePackage + "ListType"},
+		{
+			name: "id",
+			err:  `"id" is ambiguous: it could be ` + fixturePackage + "BookingStatus.id, " + fixturePackage + "Item.id, " + fixturePackage + "List.id",
+		},
+		// Only whole components match, not the end of a name
+		{name: "ist.id", err: `no object named "ist.id"`},
+	}
+
+	q := fixtureQuerier(t, new(bytes.Buffer))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := q.resolveName(test.name)
+			if len(test.err) > 0 {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("got %q (%v), want %q", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestQueryFind(t *testing.T) {
+	tests := []struct {
+		option string
+		want   []string
+	}{
+		{option: "extend_message", want: []string{fixturePackage + "Booking", fixturePackage + "List"}},
+		{option: fixturePackage + "extend_message", want: []string{fixturePackage + "Booking", fixturePackage + "List"}},
+		{option: "extend_field=true", want: []string{fixturePackage + "Booking.payment_received", fixturePackage + "List.name"}},
+		{option: "extend_field=false", want: []string{}},
+		{option: "extend_file=true", want: []string{"booking.proto", "todo.proto"}},
+		{option: "message", want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.option, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			if err := fixtureQuerier(t, w).find([]string{"-option", test.option}); err != nil {
+				t.Fatal(err)
+			}
+			rows := make([]*queryRow, 0)
+			if err := json.Unmarshal(w.Bytes(), &rows); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(rows))
+			for _, row := range rows {
+				got = append(got, row.FQN)
+				if !strings.HasPrefix(row.Detail, fixturePackage+"extend_") || row.Value != true {
+					t.Errorf("got detail %q and value %v", row.Detail, row.Value)
+				}
+				if len(row.Description) == 0 {
+					t.Errorf("%s has no description", row.FQN)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if err := fixtureQuerier(t, new(bytes.Buffer)).find(nil); err == nil {
+		t.Error("find without an option succeeded")
+	}
+}
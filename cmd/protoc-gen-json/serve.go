@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trinsic-id/protoc-gen-json/pkg/protojson"
+)
+
+// liveReloadScript is added to every page of the documentation, to reload it when the output changes
+const liveReloadScript = `<script>new EventSource("/_events").onmessage = function () { location.reload(); };</script>`
+
+// searchLimit is the maximum number of results returned by `/search`
+const searchLimit = 50
+
+// docServer serves the documentation site and a REST API for an output, reloading them when the output changes
+type docServer struct {
+	input string
+
+	mu      sync.RWMutex
+	ctx     *protojson.Context
+	site    map[string][]byte
+	modTime time.Time
+	size    int64
+	clients map[chan struct{}]bool
+}
+
+// runServe implements the `serve` subcommand, which serves the documentation of an output over HTTP
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	input := flags.String("input", "output.json", "JSON output to serve")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	poll := flags.Duration("poll", time.Second, "how often to check the output for changes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: protoc-gen-json serve [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	s := &docServer{input: *input, clients: make(map[chan struct{}]bool)}
+	if _, err := s.reload(); err != nil {
+		return err
+	}
+	go s.watch(*poll)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/entities/", s.serveEntity)
+	mux.HandleFunc("/search", s.serveSearch)
+	mux.HandleFunc("/graph/", s.serveGraph)
+	mux.HandleFunc("/_events", s.serveEvents)
+	mux.HandleFunc("/", s.serveSite)
+
+	fmt.Fprintf(os.Stderr, "protoc-gen-json: serving %s at http://%s\n", *input, *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// reload reads the output again if it changed since it was last read, reporting whether it did
+func (s *docServer) reload() (bool, error) {
+	info, err := os.Stat(s.input)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime) && info.Size() == s.size
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	ctx, err := protojson.ReadContextFile(s.input)
+	if err != nil {
+		return false, err
+	}
+
+	opts := protojson.DefaultOptions()
+	opts.Format = "html"
+	renderer, err := protojson.NewRenderer(opts)
+	if err != nil {
+		return false, err
+	}
+	files, err := renderer.Render(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	site := make(map[string][]byte)
+	for _, file := range files {
+		content := []byte(file.GetContent())
+		if strings.HasSuffix(file.GetName(), ".html") {
+			content = bytes.Replace(content, []byte("</body>"), []byte(liveReloadScript+"</body>"), 1)
+		}
+		site[file.GetName()] = content
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.site, s.modTime, s.size = ctx, site, info.ModTime(), info.Size()
+	return true, nil
+}
+
+// watch reloads the output whenever it changes, and tells the open pages to reload.
+// While the output can't be read (EG, it's being rewritten), the last one read is kept.
+func (s *docServer) watch(interval time.Duration) {
+	lastErr := ""
+	for range time.Tick(interval) {
+		changed, err := s.reload()
+		if err != nil {
+			// The output is read again on every tick until it can be, but each error is only reported once
+			if err.Error() != lastErr {
+				fmt.Fprintf(os.Stderr, "protoc-gen-json: failed to reload %s: %v\n", s.input, err)
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+		if !changed {
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "protoc-gen-json: reloaded %s\n", s.input)
+		s.mu.RLock()
+		for client := range s.clients {
+			select {
+			case client <- struct{}{}:
+			default:
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeJSONError writes a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// serveSite serves the pages and assets of the documentation site
+func (s *docServer) serveSite(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if len(name) == 0 {
+		name = "index.html"
+	}
+
+	s.mu.RLock()
+	content, found := s.site[name]
+	s.mu.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Write(content)
+}
+
+// serveEntity serves `/entities/{fqn}`: the index entry of an object, and the object itself
+func (s *docServer) serveEntity(w http.ResponseWriter, r *http.Request) {
+	fqn := protojson.GetFQN(strings.TrimPrefix(r.URL.Path, "/entities/"))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, found := s.ctx.Index[fqn]
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "no entity %q", fqn)
+		return
+	}
+	object, _ := s.ctx.Lookup(fqn)
+
+	writeJSON(w, http.StatusOK, &struct {
+		FQN    string                `json:"fqn"`
+		Entry  *protojson.IndexEntry `json:"entry"`
+		Object interface{}           `json:"object,omitempty"`
+	}{fqn, entry, object})
+}
+
+// searchResult is a match of `/search`
+type searchResult struct {
+	Kind    string `json:"kind"`
+	FQN     string `json:"fqn"`
+	File    string `json:"file"`
+	Summary string `json:"summary,omitempty"`
+
+	rank int
+}
+
+// serveSearch serves `/search?q=`: the objects whose names, FQNs or descriptions contain a query, best matches first.
+// Matches of the whole name rank first, then the start of the name, then anywhere in the FQN, then the description.
+func (s *docServer) serveSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if len(query) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	limit := searchLimit
+	if raw := r.URL.Query().Get("limit"); len(raw) > 0 {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive number, got %q", raw)
+			return
+		}
+		limit = parsed
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]*searchResult, 0)
+	for fqn, entry := range s.ctx.Index {
+		name := strings.ToLower(fqn[strings.LastIndex(fqn, ".")+1:])
+		description := entry.Description
+		if object, found := s.ctx.Lookup(fqn); found {
+			description = protojson.DescriptionOf(object)
+		}
+
+		rank := -1
+		switch {
+		case name == query:
+			rank = 0
+		case strings.HasPrefix(name, query):
+			rank = 1
+		case strings.Contains(strings.ToLower(fqn), query):
+			rank = 2
+		case strings.Contains(strings.ToLower(description), query):
+			rank = 3
+		}
+		if rank >= 0 {
+			results = append(results, &searchResult{Kind: entry.Type, FQN: fqn, File: entry.File, Summary: summary(description), rank: rank})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		return results[i].FQN < results[j].FQN
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// serveGraph serves `/graph/{fqn}`: the reference graph of an object and the types reachable from it, or of
+// everything at `/graph/`
+func (s *docServer) serveGraph(w http.ResponseWriter, r *http.Request) {
+	fqn := protojson.GetFQN(strings.TrimPrefix(r.URL.Path, "/graph/"))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, found := s.ctx.Lookup(fqn); len(fqn) > 0 && !found {
+		writeJSONError(w, http.StatusNotFound, "no entity %q", fqn)
+		return
+	}
+
+	graph, err := protojson.TypeGraphOf(s.ctx, fqn)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, graph)
+}
+
+// serveEvents streams a server-sent event to a page every time the output is reloaded
+func (s *docServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-client:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
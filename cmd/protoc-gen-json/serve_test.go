@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtureServer returns a server of the output of the test fixtures
+func fixtureServer(t *testing.T) *docServer {
+	t.Helper()
+
+	s := &docServer{input: writeFixtureOutput(t), clients: make(map[chan struct{}]bool)}
+	if reloaded, err := s.reload(); err != nil || !reloaded {
+		t.Fatalf("got %v (%v) from the first reload", reloaded, err)
+	}
+	return s
+}
+
+// serve makes a GET request to a handler of a server, and decodes its JSON response into `v`
+func serve(t *testing.T, handler http.HandlerFunc, target string, v interface{}) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	return w.Code
+}
+
+func TestServeEntity(t *testing.T) {
+	s := fixtureServer(t)
+
+	entity := struct {
+		FQN   string `json:"fqn"`
+		Entry struct {
+			Type   string `json:"type"`
+			Parent string `json:"parent"`
+		} `json:"entry"`
+		Object map[string]interface{} `json:"object"`
+	}{}
+	if code := serve(t, s.serveEntity, "/entities/."+fixturePackage+"List.name", &entity); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if entity.FQN != fixturePackage+"List.name" || entity.Entry.Type != "field" || entity.Entry.Parent != fixturePackage+"List" {
+		t.Errorf("got %+v", entity)
+	}
+	if entity.Object["name"] != "name" {
+		t.Errorf("got object %v", entity.Object)
+	}
+
+	missing := make(map[string]interface{})
+	if code := serve(t, s.serveEntity, "/entities/List", &missing); code != http.StatusNotFound {
+		t.Errorf("got status %d for a partial name", code)
+	}
+}
+
+func TestServeSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+		// summary is the summary expected of the first result
+		summary string
+	}{
+		// Whole names first, then the start of names, then FQNs
+		{
+			query:   "listtype",
+			want:    []string{fixturePackage + "ListType", fixturePackage + "ListType.CHECKLIST", fixturePackage + "ListType.REMINDERS"},
+			summary: "An enumeration of list types",
+		},
+		{query: "item&limit=3", want: []string{fixturePackage + "AddItemResponse.item", fixturePackage + "Item", fixturePackage + "AddItemRequest"}},
+		// Descriptions are searched last
+		{query: "dummy enum", want: []string{fixturePackage + "ListItemDetailEnum"}, summary: "A dummy enum to ensure importing works."},
+	}
+
+	s := fixtureServer(t)
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results := make([]*searchResult, 0)
+			if code := serve(t, s.serveSearch, "/search?q="+strings.ReplaceAll(test.query, " ", "+"), &results); code != http.StatusOK {
+				t.Fatalf("got status %d", code)
+			}
+
+			got := make([]string, 0, len(results))
+			for _, result := range results {
+				got = append(got, result.FQN)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if len(test.summary) > 0 && len(results) > 0 && results[0].Summary != test.summary {
+				t.Errorf("got summary %q, want %q", results[0].Summary, test.summary)
+			}
+		})
+	}
+
+	errorBody := make(map[string]interface{})
+	if code := serve(t, s.serveSearch, "/search?q=list&limit=0", &errorBody); code != http.StatusBadRequest {
+		t.Errorf("got status %d for a limit of 0", code)
+	}
+}
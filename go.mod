@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/golang/protobuf v1.5.2
 	github.com/pseudomuto/protokit v0.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,333 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Rules reported by the breaking change detector
+const (
+	BreakingMessageRemoved          = "MESSAGE_REMOVED"
+	BreakingFieldRemoved            = "FIELD_REMOVED"
+	BreakingFieldNumberChanged      = "FIELD_NUMBER_CHANGED"
+	BreakingFieldTypeChanged        = "FIELD_TYPE_CHANGED"
+	BreakingFieldCardinalityChanged = "FIELD_CARDINALITY_CHANGED"
+	BreakingFieldJSONNameChanged    = "FIELD_JSON_NAME_CHANGED"
+	BreakingFieldNumberReused       = "FIELD_NUMBER_REUSED"
+	BreakingEnumRemoved             = "ENUM_REMOVED"
+	BreakingEnumValueRemoved        = "ENUM_VALUE_REMOVED"
+	BreakingEnumValueNumberChanged  = "ENUM_VALUE_NUMBER_CHANGED"
+	BreakingReservedNumberReused    = "RESERVED_NUMBER_REUSED"
+	BreakingReservedNameReused      = "RESERVED_NAME_REUSED"
+	BreakingServiceRemoved          = "SERVICE_REMOVED"
+	BreakingMethodRemoved           = "METHOD_REMOVED"
+	BreakingMethodInputChanged      = "METHOD_INPUT_CHANGED"
+	BreakingMethodOutputChanged     = "METHOD_OUTPUT_CHANGED"
+	BreakingMethodStreamingChanged  = "METHOD_STREAMING_CHANGED"
+)
+
+// BreakingChange is a change which breaks wire or JSON compatibility with the baseline
+type BreakingChange struct {
+	Rule string `json:"rule"`
+	// FQN is the object which changed; for removals, it is the FQN the object had in the baseline
+	FQN     string `json:"fqn"`
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+// BreakingReport is the result of comparing a context to a baseline
+type BreakingReport struct {
+	Baseline        string            `json:"baseline"`
+	BreakingChanges []*BreakingChange `json:"breaking_changes"`
+}
+
+// checkBreakingChanges compares a context to the previous output at `baselinePath`
+func checkBreakingChanges(ctx *Context, baselinePath string) (*BreakingReport, error) {
+	baseline, err := ReadContextFile(baselinePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	checker := &breakingChecker{baseline: baseline, current: ctx, changes: make([]*BreakingChange, 0)}
+	checker.checkMessages()
+	checker.checkFields()
+	checker.checkEnums()
+	checker.checkServices()
+
+	return &BreakingReport{Baseline: baselinePath, BreakingChanges: checker.changes}, nil
+}
+
+// Error summarises the breaking changes in the report, for failing the compilation
+func (r *BreakingReport) Error() error {
+	lines := make([]string, 0, len(r.BreakingChanges)+1)
+	lines = append(lines, fmt.Sprintf("%d breaking changes against %s:", len(r.BreakingChanges), r.Baseline))
+	for _, change := range r.BreakingChanges {
+		lines = append(lines, fmt.Sprintf("  %s: %s", change.Rule, change.Message))
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// File encodes the report as a JSON output file
+func (r *BreakingReport) File(filename string, indent string) (*plugin_go.CodeGeneratorResponse_File, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(r); err != nil {
+		return nil, err
+	}
+
+	return &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String(filename),
+		Content: proto.String(buf.String()),
+	}, nil
+}
+
+// breakingChecker accumulates the breaking changes between a baseline and the current context.
+//
+// Objects are compared by FQN. When an object is removed, the objects declared in it aren't reported separately.
+type breakingChecker struct {
+	baseline *Context
+	current  *Context
+	changes  []*BreakingChange
+}
+
+func (c *breakingChecker) report(rule string, fqn string, format string, args ...interface{}) {
+	change := &BreakingChange{Rule: rule, FQN: fqn, Message: fmt.Sprintf(format, args...)}
+	if entry, found := c.current.Index[fqn]; found {
+		change.File = entry.File
+	} else if entry, found := c.baseline.Index[fqn]; found {
+		change.File = entry.File
+	}
+	c.changes = append(c.changes, change)
+}
+
+// baselineBefore reports whether the baseline was written in a version of the output format older than `version`
+func (c *breakingChecker) baselineBefore(version string) bool {
+	// Only outputs of version 1.0 have no header
+	if c.baseline.Meta == nil {
+		return schemaVersionBefore(outputSchemaChanges[0].Version, version)
+	}
+	return schemaVersionBefore(c.baseline.Meta.SchemaVersion, version)
+}
+
+// parentRemoved reports whether the object declaring the baseline object `fqn` has been removed
+func (c *breakingChecker) parentRemoved(fqn string) bool {
+	entry, found := c.baseline.Index[fqn]
+	if !found {
+		return false
+	}
+	parent := parentName(fqn, entry)
+	if len(parent) == 0 {
+		return false
+	}
+	_, stillIndexed := c.current.Index[parent]
+	return !stillIndexed
+}
+
+func (c *breakingChecker) checkMessages() {
+	for _, fqn := range sortedKeys(c.baseline.Messages) {
+		before := c.baseline.Messages[fqn]
+		after, found := c.current.Messages[fqn]
+
+		// Map entries are checked through the fields which use them
+		if !found {
+			if !before.IsMapEntry && !c.parentRemoved(fqn) {
+				c.report(BreakingMessageRemoved, fqn, "message %s was removed", fqn)
+			}
+			continue
+		}
+
+		numbers := make(map[int32]*Field)
+		for _, fieldName := range before.Fields {
+			if field, found := c.baseline.Fields[fieldName]; found && field.Number != 0 {
+				numbers[field.Number] = field
+			}
+		}
+
+		for _, fieldName := range after.Fields {
+			field := c.current.Fields[fieldName]
+			if field == nil {
+				continue
+			}
+
+			if previous, found := numbers[field.Number]; found && previous.Name != field.Name {
+				if _, stillExists := c.current.Fields[previous.FullName]; !stillExists {
+					c.report(BreakingFieldNumberReused, field.FullName, "field %s reuses number %d, which was used by %s",
+						field.FullName, field.Number, previous.FullName)
+				}
+			}
+			if _, existed := c.baseline.Fields[field.FullName]; existed {
+				continue
+			}
+
+			// Only new fields can reuse reserved numbers and names; existing ones are checked by number and name
+			for _, reserved := range before.ReservedRanges {
+				if reserved.Contains(field.Number) {
+					c.report(BreakingReservedNumberReused, field.FullName, "field %s uses number %d, which was reserved",
+						field.FullName, field.Number)
+					break
+				}
+			}
+			if containsString(before.ReservedNames, field.Name) {
+				c.report(BreakingReservedNameReused, field.FullName, "field %s uses the name %q, which was reserved",
+					field.FullName, field.Name)
+			}
+		}
+	}
+}
+
+func (c *breakingChecker) checkFields() {
+	for _, fqn := range sortedKeys(c.baseline.Fields) {
+		before := c.baseline.Fields[fqn]
+		after, found := c.current.Fields[fqn]
+		if !found {
+			if !c.parentRemoved(fqn) {
+				c.report(BreakingFieldRemoved, fqn, "field %s was removed", fqn)
+			}
+			continue
+		}
+
+		// Outputs of older schema versions don't have numbers or JSON names, so they can't be compared
+		if before.Number != 0 && before.Number != after.Number {
+			c.report(BreakingFieldNumberChanged, fqn, "field %s changed number from %d to %d", fqn, before.Number, after.Number)
+		}
+		if before.FullType != after.FullType {
+			c.report(BreakingFieldTypeChanged, fqn, "field %s changed type from %s to %s", fqn, before.FullType, after.FullType)
+		}
+		if beforeCardinality, afterCardinality := fieldCardinality(c.baseline, before), fieldCardinality(c.current, after); beforeCardinality != afterCardinality {
+			c.report(BreakingFieldCardinalityChanged, fqn, "field %s changed from %s to %s", fqn, beforeCardinality, afterCardinality)
+		}
+		if len(before.JSONName) > 0 && before.JSONName != after.JSONName {
+			c.report(BreakingFieldJSONNameChanged, fqn, "field %s changed JSON name from %q to %q", fqn, before.JSONName, after.JSONName)
+		}
+	}
+}
+
+func (c *breakingChecker) checkEnums() {
+	for _, fqn := range sortedKeys(c.baseline.Enums) {
+		before := c.baseline.Enums[fqn]
+		after, found := c.current.Enums[fqn]
+		if !found {
+			if !c.parentRemoved(fqn) {
+				c.report(BreakingEnumRemoved, fqn, "enum %s was removed", fqn)
+			}
+			continue
+		}
+
+		for _, valueName := range after.Values {
+			value := c.current.EnumValues[valueName]
+			if _, existed := c.baseline.EnumValues[valueName]; existed || value == nil {
+				continue
+			}
+
+			for _, reserved := range before.ReservedRanges {
+				if reserved.Contains(value.Value) {
+					c.report(BreakingReservedNumberReused, value.FullName, "enum value %s uses number %d, which was reserved",
+						value.FullName, value.Value)
+					break
+				}
+			}
+			if containsString(before.ReservedNames, value.Name) {
+				c.report(BreakingReservedNameReused, value.FullName, "enum value %s uses the name %q, which was reserved",
+					value.FullName, value.Name)
+			}
+		}
+	}
+
+	for _, fqn := range sortedKeys(c.baseline.EnumValues) {
+		before := c.baseline.EnumValues[fqn]
+		after, found := c.current.EnumValues[fqn]
+		if !found {
+			if !c.parentRemoved(fqn) {
+				c.report(BreakingEnumValueRemoved, fqn, "enum value %s was removed", fqn)
+			}
+			continue
+		}
+
+		if before.Value != after.Value {
+			c.report(BreakingEnumValueNumberChanged, fqn, "enum value %s changed number from %d to %d", fqn, before.Value, after.Value)
+		}
+	}
+}
+
+func (c *breakingChecker) checkServices() {
+	for _, fqn := range sortedKeys(c.baseline.Services) {
+		if _, found := c.current.Services[fqn]; !found {
+			c.report(BreakingServiceRemoved, fqn, "service %s was removed", fqn)
+		}
+	}
+
+	for _, fqn := range sortedKeys(c.baseline.Methods) {
+		before := c.baseline.Methods[fqn]
+		after, found := c.current.Methods[fqn]
+		if !found {
+			if !c.parentRemoved(fqn) {
+				c.report(BreakingMethodRemoved, fqn, "method %s was removed", fqn)
+			}
+			continue
+		}
+
+		if before.InputType != after.InputType {
+			c.report(BreakingMethodInputChanged, fqn, "method %s changed request type from %s to %s", fqn, before.InputType, after.InputType)
+		}
+		if before.OutputType != after.OutputType {
+			c.report(BreakingMethodOutputChanged, fqn, "method %s changed response type from %s to %s", fqn, before.OutputType, after.OutputType)
+		}
+		// Outputs of schema versions before 1.2 don't say which methods stream, so they can't be compared
+		streamingChanged := before.ClientStreaming != after.ClientStreaming || before.ServerStreaming != after.ServerStreaming
+		if streamingChanged && !c.baselineBefore("1.2") {
+			c.report(BreakingMethodStreamingChanged, fqn, "method %s changed from %s to %s", fqn, methodStreaming(before), methodStreaming(after))
+		}
+	}
+}
+
+// fieldCardinality returns `map`, or the label of a field without its `LABEL_` prefix
+func fieldCardinality(ctx *Context, field *Field) string {
+	if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED.String() {
+		if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry {
+			return "map"
+		}
+	}
+	return strings.ToLower(strings.TrimPrefix(field.Label, "LABEL_"))
+}
+
+// methodStreaming describes which sides of a method stream
+func methodStreaming(method *Method) string {
+	switch {
+	case method.ClientStreaming && method.ServerStreaming:
+		return "bidirectional streaming"
+	case method.ClientStreaming:
+		return "client streaming"
+	case method.ServerStreaming:
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
+
+// sortedKeys returns the keys of a collection, sorted
+func sortedKeys[T any](collection map[string]*T) []string {
+	ret := make([]string, 0, len(collection))
+	for key := range collection {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// containsString reports whether `values` contains `value`
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
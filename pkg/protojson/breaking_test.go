@@ -0,0 +1,133 @@
+package protojson
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestBreakingChanges(t *testing.T) {
+	// Baselines are written in each version of the output format
+	baselines := make(map[string]string)
+	for _, change := range outputSchemaChanges {
+		baselines[change.Version] = filepath.Join(t.TempDir(), "baseline.json")
+		output := generateFixture(t, fixtureRequest(t, "schema_version="+change.Version))["output.json"]
+		if err := os.WriteFile(baselines[change.Version], []byte(output), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		// baseline is the version of the baseline, if not the current one
+		baseline string
+		generate []string
+		change   func(t *testing.T, req *pluginpb.CodeGeneratorRequest)
+		// want are the changes expected, as "RULE fqn"
+		want []string
+	}{
+		{
+			name: "unchanged",
+			want: []string{},
+		},
+		{
+			name:     "removed file",
+			generate: []string{"extend.proto", "todo.proto", "todo_import.proto"},
+			want: []string{
+				"ENUM_REMOVED com.pseudomuto.protokit.v1.BookingType",
+				"MESSAGE_REMOVED com.pseudomuto.protokit.v1.Booking",
+				"MESSAGE_REMOVED com.pseudomuto.protokit.v1.BookingStatus",
+				"SERVICE_REMOVED com.pseudomuto.protokit.v1.BookingService",
+			},
+		},
+		{
+			name: "removed method",
+			change: func(t *testing.T, req *pluginpb.CodeGeneratorRequest) {
+				service := fixtureFile(t, req, "todo.proto").GetService()[0]
+				service.Method = service.Method[:1]
+			},
+			want: []string{"METHOD_REMOVED com.pseudomuto.protokit.v1.Todo.AddItem"},
+		},
+		{
+			name: "renumbered field",
+			change: func(t *testing.T, req *pluginpb.CodeGeneratorRequest) {
+				field := fixtureFile(t, req, "todo.proto").GetMessageType()[1].GetField()[0]
+				field.Number = &[]int32{10}[0]
+			},
+			want: []string{"FIELD_NUMBER_CHANGED com.pseudomuto.protokit.v1.CreateListRequest.name"},
+		},
+		{
+			name:     "renumbered field against 1.0",
+			baseline: "1.0",
+			change: func(t *testing.T, req *pluginpb.CodeGeneratorRequest) {
+				field := fixtureFile(t, req, "todo.proto").GetMessageType()[1].GetField()[0]
+				field.Number = &[]int32{10}[0]
+			},
+			want: []string{},
+		},
+		{
+			name:   "streaming method",
+			change: streamAddItem,
+			want:   []string{"METHOD_STREAMING_CHANGED com.pseudomuto.protokit.v1.Todo.AddItem"},
+		},
+		{
+			name:     "streaming method against 1.2",
+			baseline: "1.2",
+			change:   streamAddItem,
+			want:     []string{"METHOD_STREAMING_CHANGED com.pseudomuto.protokit.v1.Todo.AddItem"},
+		},
+		{
+			name:     "streaming method against 1.0",
+			baseline: "1.0",
+			change:   streamAddItem,
+			want:     []string{},
+		},
+		{
+			name:     "removed method against 1.0",
+			baseline: "1.0",
+			change: func(t *testing.T, req *pluginpb.CodeGeneratorRequest) {
+				service := fixtureFile(t, req, "todo.proto").GetService()[0]
+				service.Method = service.Method[:1]
+			},
+			want: []string{"METHOD_REMOVED com.pseudomuto.protokit.v1.Todo.AddItem"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := fixtureRequest(t, "", test.generate...)
+			if test.change != nil {
+				test.change(t, req)
+			}
+
+			baseline := baselines[OutputSchemaVersion]
+			if len(test.baseline) > 0 {
+				baseline = baselines[test.baseline]
+			}
+			report, err := checkBreakingChanges(buildFixture(t, req), baseline)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0)
+			for _, change := range report.BreakingChanges {
+				got = append(got, change.Rule+" "+change.FQN)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got changes %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// streamAddItem makes the fixture's `Todo.AddItem` method return a stream
+func streamAddItem(t *testing.T, req *pluginpb.CodeGeneratorRequest) {
+	method := fixtureFile(t, req, "todo.proto").GetService()[0].GetMethod()[1]
+	method.ServerStreaming = proto.Bool(true)
+}
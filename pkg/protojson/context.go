@@ -1,4 +1,4 @@
-package main
+package protojson
 
 import (
 	"github.com/pseudomuto/protokit"
@@ -7,6 +7,7 @@ import (
 // Context carries context throughout the compilation process, and is output as JSON
 type Context struct {
 	CustomOptions *CustomOptions         `json:"-"`
+	Meta          *Meta                  `json:"meta"`
 	Index         map[string]*IndexEntry `json:"index"`
 
 	Files      map[string]*File      `json:"files"`
@@ -23,6 +24,11 @@ type IndexEntry struct {
 	Collection string `json:"collection"`
 	File       string `json:"file"`
 	Parent     string `json:"parent"`
+
+	// External is set on the stubs of types declared in files which aren't generated; they aren't in any collection
+	External bool `json:"external,omitempty"`
+	// Description is the comment on an external type, since it can't be looked up in a collection
+	Description string `json:"description,omitempty"`
 }
 
 // File is a parsed protobuf file
@@ -39,6 +45,9 @@ type File struct {
 	Fields      []string               `json:"fields"`
 	Enums       []string               `json:"enums"`
 	EnumValues  []string               `json:"enum_values"`
+
+	// DeclarationIndex is the position of this file among the files being generated
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 // Service is a parsed service defined in a File
@@ -50,6 +59,9 @@ type Service struct {
 	Description string                 `json:"description"`
 	Methods     []string               `json:"methods"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+
+	// DeclarationIndex is the position of this service within its file
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 // Method is a parsed service method
@@ -62,6 +74,15 @@ type Method struct {
 	OutputType  string                 `json:"output_type"`
 	Description string                 `json:"description"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+	HTTPRules   []*HTTPRule            `json:"http_rules,omitempty"`
+
+	// ClientStreaming and ServerStreaming are set on methods which take a stream of requests, or return a stream of
+	// responses
+	ClientStreaming bool `json:"client_streaming,omitempty"`
+	ServerStreaming bool `json:"server_streaming,omitempty"`
+
+	// DeclarationIndex is the position of this method within its service
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 // Message is a parsed message defined in a file
@@ -76,6 +97,21 @@ type Message struct {
 	Fields      []string               `json:"fields"`
 	Messages    []string               `json:"messages"`
 	Enums       []string               `json:"enums"`
+
+	// ReservedRanges and ReservedNames are the field numbers and names which may not be used by fields
+	ReservedRanges []*ReservedRange `json:"reserved_ranges,omitempty"`
+	ReservedNames  []string         `json:"reserved_names,omitempty"`
+
+	// IsRecursive is set on messages which contain themselves, directly or through other messages. SCC identifies the
+	// strongly connected component of the message graph the message belongs to (see `markCycles`).
+	IsRecursive bool   `json:"is_recursive"`
+	SCC         string `json:"scc"`
+
+	// Example is an example instance of the message in canonical proto3 JSON (see `generateExamples`)
+	Example interface{} `json:"example,omitempty"`
+
+	// DeclarationIndex is the position of this message within its parent message or file
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 // Field is a parsed field defined in a Message
@@ -84,11 +120,23 @@ type Field struct {
 
 	Name        string                 `json:"name"`
 	FullName    string                 `json:"full_name"`
+	JSONName    string                 `json:"json_name"`
+	Number      int32                  `json:"number"`
 	Label       string                 `json:"label"`
 	Type        string                 `json:"type"`
 	FullType    string                 `json:"full_type"`
+	Oneof       string                 `json:"oneof,omitempty"`
 	Description string                 `json:"description"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+
+	// CreatesCycle is set on fields whose type contains the field's own message, directly or indirectly
+	CreatesCycle bool `json:"creates_cycle"`
+
+	// WellKnownType describes the type of the field if it's a well-known type, such as `google.protobuf.Timestamp`
+	WellKnownType *WellKnownType `json:"well_known_type,omitempty"`
+
+	// DeclarationIndex is the position of this field within its message
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 type Enum struct {
@@ -99,6 +147,13 @@ type Enum struct {
 	Description string                 `json:"description"`
 	Values      []string               `json:"values"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+
+	// ReservedRanges and ReservedNames are the numbers and names which may not be used by values
+	ReservedRanges []*ReservedRange `json:"reserved_ranges,omitempty"`
+	ReservedNames  []string         `json:"reserved_names,omitempty"`
+
+	// DeclarationIndex is the position of this enum within its parent message or file
+	DeclarationIndex int `json:"declaration_index"`
 }
 
 type EnumValue struct {
@@ -109,6 +164,20 @@ type EnumValue struct {
 	Description string                 `json:"description"`
 	Value       int32                  `json:"value"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+
+	// DeclarationIndex is the position of this value within its enum
+	DeclarationIndex int `json:"declaration_index"`
+}
+
+// ReservedRange is an inclusive range of reserved field or enum value numbers
+type ReservedRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+// Contains reports whether `number` is within the range
+func (r *ReservedRange) Contains(number int32) bool {
+	return number >= r.Start && number <= r.End
 }
 
 func NewContext() *Context {
@@ -224,3 +293,58 @@ func (ctx *Context) StoreMethod(method *Method, methodProto *protokit.MethodDesc
 
 	ctx.Index[GetFQN(methodProto.GetFullName())] = entry
 }
+
+// Lookup returns the object with the FQN `fqn`, using the index to find its collection
+func (ctx *Context) Lookup(fqn string) (interface{}, bool) {
+	entry, found := ctx.Index[fqn]
+	if !found {
+		return nil, false
+	}
+
+	var ret interface{}
+	switch entry.Collection {
+	case "services":
+		ret, found = ctx.Services[fqn]
+	case "methods":
+		ret, found = ctx.Methods[fqn]
+	case "messages":
+		ret, found = ctx.Messages[fqn]
+	case "fields":
+		ret, found = ctx.Fields[fqn]
+	case "enums":
+		ret, found = ctx.Enums[fqn]
+	case "enum_values":
+		ret, found = ctx.EnumValues[fqn]
+	default:
+		found = false
+	}
+
+	return ret, found
+}
+
+// MethodsOf returns the methods of a service, in declaration order
+func (ctx *Context) MethodsOf(service *Service) []*Method {
+	ret := make([]*Method, 0, len(service.Methods))
+	for _, fqn := range service.Methods {
+		ret = append(ret, ctx.Methods[fqn])
+	}
+	return ret
+}
+
+// FieldsOf returns the fields of a message, in declaration order
+func (ctx *Context) FieldsOf(message *Message) []*Field {
+	ret := make([]*Field, 0, len(message.Fields))
+	for _, fqn := range message.Fields {
+		ret = append(ret, ctx.Fields[fqn])
+	}
+	return ret
+}
+
+// ValuesOf returns the values of an enum, in declaration order
+func (ctx *Context) ValuesOf(enum *Enum) []*EnumValue {
+	ret := make([]*EnumValue, 0, len(enum.Values))
+	for _, fqn := range enum.Values {
+		ret = append(ret, ctx.EnumValues[fqn])
+	}
+	return ret
+}
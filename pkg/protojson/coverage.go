@@ -0,0 +1,201 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// Coverage is the number of objects which are documented, out of a total
+type Coverage struct {
+	Documented int     `json:"documented"`
+	Total      int     `json:"total"`
+	Percent    float64 `json:"percent"`
+}
+
+// add counts an object
+func (c *Coverage) add(documented bool) {
+	c.Total++
+	if documented {
+		c.Documented++
+	}
+	c.Percent = coveragePercent(c.Documented, c.Total)
+}
+
+func (c *Coverage) String() string {
+	return fmt.Sprintf("%.1f%% (%d/%d)", c.Percent, c.Documented, c.Total)
+}
+
+// CoverageBreakdown is the documentation coverage of a set of objects, overall and by kind
+type CoverageBreakdown struct {
+	Coverage
+
+	Services   *Coverage `json:"services"`
+	Methods    *Coverage `json:"methods"`
+	Messages   *Coverage `json:"messages"`
+	Fields     *Coverage `json:"fields"`
+	Enums      *Coverage `json:"enums"`
+	EnumValues *Coverage `json:"enum_values"`
+}
+
+func newCoverageBreakdown() *CoverageBreakdown {
+	return &CoverageBreakdown{
+		Coverage:   Coverage{Percent: 100},
+		Services:   &Coverage{Percent: 100},
+		Methods:    &Coverage{Percent: 100},
+		Messages:   &Coverage{Percent: 100},
+		Fields:     &Coverage{Percent: 100},
+		Enums:      &Coverage{Percent: 100},
+		EnumValues: &Coverage{Percent: 100},
+	}
+}
+
+// CoverageReport is the documentation coverage of a context, overall, per package and per file
+type CoverageReport struct {
+	Total    *CoverageBreakdown            `json:"total"`
+	Packages map[string]*CoverageBreakdown `json:"packages"`
+	Files    map[string]*CoverageBreakdown `json:"files"`
+}
+
+// isDocumented reports whether an object with the description `description` is documented
+func isDocumented(description string) bool {
+	return len(strings.TrimSpace(description)) > 0
+}
+
+// coveragePercent returns `documented` as a percentage of `total`, rounded to 2 decimal places.
+// Nothing to document counts as full coverage.
+func coveragePercent(documented int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(documented)*10000/float64(total)) / 100
+}
+
+// measureCoverage measures the documentation coverage of every object in a context.
+// Map entries are synthesized by `protoc`, so they and their fields aren't counted.
+func measureCoverage(ctx *Context) *CoverageReport {
+	report := &CoverageReport{
+		Total:    newCoverageBreakdown(),
+		Packages: make(map[string]*CoverageBreakdown),
+		Files:    make(map[string]*CoverageBreakdown),
+	}
+
+	count := func(fqn string, description string, kind func(b *CoverageBreakdown) *Coverage) {
+		entry, found := ctx.Index[fqn]
+		if !found {
+			return
+		}
+		file, found := ctx.Files[entry.File]
+		if !found {
+			return
+		}
+
+		if _, found := report.Files[file.Name]; !found {
+			report.Files[file.Name] = newCoverageBreakdown()
+		}
+		if _, found := report.Packages[file.Package]; !found {
+			report.Packages[file.Package] = newCoverageBreakdown()
+		}
+
+		documented := isDocumented(description)
+		for _, breakdown := range []*CoverageBreakdown{report.Total, report.Packages[file.Package], report.Files[file.Name]} {
+			breakdown.add(documented)
+			kind(breakdown).add(documented)
+		}
+	}
+
+	for fqn, service := range ctx.Services {
+		count(fqn, service.Description, func(b *CoverageBreakdown) *Coverage { return b.Services })
+	}
+	for fqn, method := range ctx.Methods {
+		count(fqn, method.Description, func(b *CoverageBreakdown) *Coverage { return b.Methods })
+	}
+	for fqn, message := range ctx.Messages {
+		if !message.IsMapEntry {
+			count(fqn, message.Description, func(b *CoverageBreakdown) *Coverage { return b.Messages })
+		}
+	}
+	for fqn, field := range ctx.Fields {
+		if parent, found := ctx.Messages[ctx.Index[fqn].Parent]; !found || !parent.IsMapEntry {
+			count(fqn, field.Description, func(b *CoverageBreakdown) *Coverage { return b.Fields })
+		}
+	}
+	for fqn, enum := range ctx.Enums {
+		count(fqn, enum.Description, func(b *CoverageBreakdown) *Coverage { return b.Enums })
+	}
+	for fqn, value := range ctx.EnumValues {
+		count(fqn, value.Description, func(b *CoverageBreakdown) *Coverage { return b.EnumValues })
+	}
+
+	return report
+}
+
+// String summarises the report as text, with the overall coverage followed by each kind, package and file
+func (r *CoverageReport) String() string {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "documentation coverage %s\n", &r.Total.Coverage)
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	kinds := []struct {
+		name     string
+		coverage *Coverage
+	}{
+		{"services", r.Total.Services},
+		{"methods", r.Total.Methods},
+		{"messages", r.Total.Messages},
+		{"fields", r.Total.Fields},
+		{"enums", r.Total.Enums},
+		{"enum values", r.Total.EnumValues},
+	}
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "  %s\t%s\n", kind.name, kind.coverage)
+	}
+	for _, name := range sortedKeys(r.Packages) {
+		fmt.Fprintf(w, "  package %s\t%s\n", name, &r.Packages[name].Coverage)
+	}
+	for _, name := range sortedKeys(r.Files) {
+		fmt.Fprintf(w, "  file %s\t%s\n", name, &r.Files[name].Coverage)
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+// File encodes the report as a JSON output file
+func (r *CoverageReport) File(filename string, indent string) (*plugin_go.CodeGeneratorResponse_File, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(r); err != nil {
+		return nil, err
+	}
+
+	return &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String(filename),
+		Content: proto.String(buf.String()),
+	}, nil
+}
+
+// belowThreshold returns an error if the overall coverage is below `threshold` percent
+func (r *CoverageReport) belowThreshold(threshold float64) error {
+	if r.Total.Percent >= threshold {
+		return nil
+	}
+
+	// List the worst-documented files first, as that's where to start
+	files := sortedKeys(r.Files)
+	sort.SliceStable(files, func(i, j int) bool { return r.Files[files[i]].Percent < r.Files[files[j]].Percent })
+
+	lines := []string{fmt.Sprintf("documentation coverage %s is below the threshold of %g%%", &r.Total.Coverage, threshold)}
+	for _, name := range files {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, &r.Files[name].Coverage))
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,97 @@
+package protojson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoveragePercent(t *testing.T) {
+	tests := []struct {
+		documented int
+		total      int
+		want       float64
+	}{
+		{documented: 0, total: 0, want: 100},
+		{documented: 0, total: 4, want: 0},
+		{documented: 1, total: 3, want: 33.33},
+		{documented: 2, total: 3, want: 66.67},
+		{documented: 1, total: 8, want: 12.5},
+		{documented: 3, total: 3, want: 100},
+	}
+
+	for _, test := range tests {
+		if got := coveragePercent(test.documented, test.total); got != test.want {
+			t.Errorf("got %v%% for %d/%d, want %v%%", got, test.documented, test.total, test.want)
+		}
+	}
+}
+
+func TestMeasureCoverage(t *testing.T) {
+	// Every object in the fixtures is documented
+	documented := measureCoverage(buildFixture(t, fixtureRequest(t, "")))
+	if documented.Total.Percent != 100 || documented.Total.Total == 0 {
+		t.Fatalf("got coverage %s of the fixtures", &documented.Total.Coverage)
+	}
+
+	// Two undocumented messages with three undocumented fields are added to todo.proto; map entries aren't counted
+	report := measureCoverage(lintFixture(t))
+
+	tests := []struct {
+		name   string
+		before *CoverageBreakdown
+		after  *CoverageBreakdown
+	}{
+		{name: "total", before: documented.Total, after: report.Total},
+		{name: "package", before: documented.Packages["com.pseudomuto.protokit.v1"], after: report.Packages["com.pseudomuto.protokit.v1"]},
+		{name: "todo.proto", before: documented.Files["todo.proto"], after: report.Files["todo.proto"]},
+	}
+	for _, test := range tests {
+		if test.before == nil || test.after == nil {
+			t.Fatalf("%s isn't measured", test.name)
+		}
+		if got, want := test.after.Messages.Total, test.before.Messages.Total+2; got != want {
+			t.Errorf("%s: got %d messages, want %d", test.name, got, want)
+		}
+		if got, want := test.after.Fields.Total, test.before.Fields.Total+3; got != want {
+			t.Errorf("%s: got %d fields, want %d", test.name, got, want)
+		}
+		if got, want := test.after.Documented, test.before.Documented; got != want {
+			t.Errorf("%s: got %d documented, want %d", test.name, got, want)
+		}
+		if got, want := test.after.Percent, coveragePercent(test.after.Documented, test.after.Total); got != want || got == 100 {
+			t.Errorf("%s: got %v%%, want %v%%", test.name, got, want)
+		}
+	}
+	if got := report.Files["booking.proto"]; got.Percent != 100 || got.Total != documented.Files["booking.proto"].Total {
+		t.Errorf("got coverage %s of booking.proto", &got.Coverage)
+	}
+
+	// The threshold is inclusive, and the files are listed worst first when it isn't met
+	if err := report.belowThreshold(report.Total.Percent); err != nil {
+		t.Errorf("got error %v at the threshold", err)
+	}
+	err := report.belowThreshold(100)
+	if err == nil {
+		t.Fatal("no error below the threshold")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if !strings.Contains(lines[0], "below the threshold of 100%") || !strings.HasPrefix(lines[1], "  todo.proto: ") {
+		t.Errorf("got error %q", err)
+	}
+}
+
+func TestCoverageMin(t *testing.T) {
+	req := fixtureRequest(t, "coverage_min=100")
+	addTestMessages(t, req, testMessage("Undocumented"))
+	warnings := make([]string, 0)
+	resp, err := Generate(req, func(message string) { warnings = append(warnings, message) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.GetError(), "below the threshold of 100%") || len(resp.GetFile()) > 0 {
+		t.Errorf("got error %q and %d files", resp.GetError(), len(resp.GetFile()))
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "documentation coverage ") {
+		t.Errorf("got warnings %q", warnings)
+	}
+}
@@ -0,0 +1,93 @@
+package protojson
+
+// markCycles finds the strongly connected components of the message graph, in which each message has an edge to the
+// type of each of its message fields, and marks recursive messages and the fields which close their cycles.
+//
+// Components are identified by the alphabetically first FQN among their messages, so the identifiers don't change when
+// unrelated messages are added or removed. Messages which aren't in the context (EG imported well-known types) can't
+// be analyzed, so cycles through them aren't detected.
+func markCycles(ctx *Context) {
+	order := ctx.declaredOrder()
+
+	// Tarjan's algorithm, numbering components as they're completed
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	components := make([][]string, 0)
+
+	var visit func(fqn string)
+	visit = func(fqn string) {
+		index[fqn] = len(index)
+		lowLink[fqn] = index[fqn]
+		stack = append(stack, fqn)
+		onStack[fqn] = true
+
+		for _, fieldName := range ctx.Messages[fqn].Fields {
+			target := ctx.Fields[fieldName].FullType
+			if _, isMessage := ctx.Messages[target]; !isMessage {
+				continue
+			}
+
+			if _, visited := index[target]; !visited {
+				visit(target)
+				if lowLink[target] < lowLink[fqn] {
+					lowLink[fqn] = lowLink[target]
+				}
+			} else if onStack[target] && index[target] < lowLink[fqn] {
+				lowLink[fqn] = index[target]
+			}
+		}
+
+		if lowLink[fqn] == index[fqn] {
+			component := make([]string, 0)
+			for {
+				member := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[member] = false
+				component = append(component, member)
+				if member == fqn {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, fqn := range order.Messages {
+		if _, visited := index[fqn]; !visited {
+			visit(fqn)
+		}
+	}
+
+	// Identify each component by its first member, so the identifiers are stable across runs and inputs
+	componentOf := make(map[string]int)
+	for i, component := range components {
+		first := component[0]
+		for _, member := range component {
+			componentOf[member] = i
+			if member < first {
+				first = member
+			}
+		}
+		for _, member := range component {
+			ctx.Messages[member].SCC = first
+		}
+	}
+
+	// A field closes a cycle if its type is in its own message's component. Messages are recursive if they're in a
+	// component with other messages, or if they contain themselves directly.
+	for _, fqn := range order.Fields {
+		field := ctx.Fields[fqn]
+		message, found := ctx.Messages[ctx.Index[fqn].Parent]
+		target, isMessage := ctx.Messages[field.FullType]
+		if !found || !isMessage {
+			continue
+		}
+
+		if componentOf[message.FullName] == componentOf[target.FullName] {
+			field.CreatesCycle = true
+			message.IsRecursive = true
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package protojson
+
+import (
+	"testing"
+)
+
+func TestMarkCycles(t *testing.T) {
+	req := fixtureRequest(t, "")
+	addTestMessages(t, req,
+		testMessage("Self", [2]string{"name", ""}, [2]string{"self", "Self"}),
+		testMessage("Ring", [2]string{"next", "Link"}),
+		testMessage("Link", [2]string{"next", "Chain"}),
+		testMessage("Chain", [2]string{"next", "Ring"}, [2]string{"self", "Self"}),
+		testMessage("Holder", [2]string{"ring", "Ring"}, [2]string{"plain", "Plain"}),
+		testMessage("Plain", [2]string{"name", ""}),
+	)
+	ctx := buildFixture(t, req)
+
+	tests := []struct {
+		message   string
+		recursive bool
+		scc       string
+		// cycles are the fields which close a cycle
+		cycles []string
+	}{
+		{message: "Self", recursive: true, scc: "Self", cycles: []string{"self"}},
+		{message: "Ring", recursive: true, scc: "Chain", cycles: []string{"next"}},
+		{message: "Link", recursive: true, scc: "Chain", cycles: []string{"next"}},
+		{message: "Chain", recursive: true, scc: "Chain", cycles: []string{"next"}},
+		{message: "Holder", scc: "Holder"},
+		{message: "Plain", scc: "Plain"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.message, func(t *testing.T) {
+			message := ctx.Messages[fixturePackage+test.message]
+			if message.IsRecursive != test.recursive {
+				t.Errorf("got is_recursive %v", message.IsRecursive)
+			}
+			if message.SCC != fixturePackage+test.scc {
+				t.Errorf("got scc %q, want %q", message.SCC, fixturePackage+test.scc)
+			}
+			for _, fqn := range message.Fields {
+				field := ctx.Fields[fqn]
+				if want := containsString(test.cycles, field.Name); field.CreatesCycle != want {
+					t.Errorf("got creates_cycle %v on %s", field.CreatesCycle, field.Name)
+				}
+			}
+		})
+	}
+
+	// Components keep their identifiers when unrelated files are left out
+	req = fixtureRequest(t, "", "todo.proto")
+	addTestMessages(t, req, testMessage("Ring", [2]string{"next", "Link"}), testMessage("Link", [2]string{"next", "Ring"}))
+	without := buildFixture(t, req)
+	for fqn, message := range without.Messages {
+		if full, found := ctx.Messages[fqn]; found && fqn != fixturePackage+"Ring" && fqn != fixturePackage+"Link" && full.SCC != message.SCC {
+			t.Errorf("%s moved from component %q to %q", fqn, full.SCC, message.SCC)
+		}
+	}
+	if got := without.Messages[fixturePackage+"Link"].SCC; got != fixturePackage+"Link" {
+		t.Errorf("got scc %q for Link", got)
+	}
+}
@@ -0,0 +1,246 @@
+package protojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffEntry is an object which was added, removed or modified between two outputs
+type DiffEntry struct {
+	// Kind is the type of the object, as in `IndexEntry.Type`, or "file"
+	Kind string `json:"kind"`
+	FQN  string `json:"fqn"`
+	File string `json:"file,omitempty"`
+	// Changes are the keys of a modified object which changed
+	Changes []*DiffChange `json:"changes,omitempty"`
+}
+
+// DiffChange is the before and after value of a key of a modified object.
+// Options are compared individually, with keys such as `options.deprecated`.
+type DiffChange struct {
+	Key    string      `json:"key"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// SemanticDiff is the difference between two outputs, keyed by FQN
+type SemanticDiff struct {
+	Old      string       `json:"old"`
+	New      string       `json:"new"`
+	Added    []*DiffEntry `json:"added"`
+	Removed  []*DiffEntry `json:"removed"`
+	Modified []*DiffEntry `json:"modified"`
+}
+
+// diffIgnoredKeys are the keys which aren't compared, by kind.
+// Lists of the objects declared within an object are ignored, since those objects are diffed themselves.
+var diffIgnoredKeys = map[string][]string{
+	"file":    {"services", "methods", "messages", "fields", "enums", "enum_values"},
+	"service": {"methods"},
+	"message": {"fields", "messages", "enums"},
+	"enum":    {"values"},
+}
+
+// diffDerivedKeys are the keys which are computed from other objects rather than declared, by kind. They change
+// whenever what they're computed from does, and that change is reported on the objects it's declared on instead.
+var diffDerivedKeys = map[string][]string{
+	"message": {"is_recursive", "scc", "example"},
+	"field":   {"creates_cycle", "well_known_type"},
+}
+
+// Diff compares every object in two contexts by FQN. The metadata header isn't compared, since it describes how each
+// output was generated rather than what it contains.
+func Diff(before *Context, after *Context) (*SemanticDiff, error) {
+	diff := &SemanticDiff{
+		Added:    make([]*DiffEntry, 0),
+		Removed:  make([]*DiffEntry, 0),
+		Modified: make([]*DiffEntry, 0),
+	}
+
+	collections := []struct {
+		kind   string
+		before map[string]interface{}
+		after  map[string]interface{}
+	}{
+		{"file", genericCollection(before.Files), genericCollection(after.Files)},
+		{"service", genericCollection(before.Services), genericCollection(after.Services)},
+		{"method", genericCollection(before.Methods), genericCollection(after.Methods)},
+		{"message", genericCollection(before.Messages), genericCollection(after.Messages)},
+		{"field", genericCollection(before.Fields), genericCollection(after.Fields)},
+		{"enum", genericCollection(before.Enums), genericCollection(after.Enums)},
+		{"enum_value", genericCollection(before.EnumValues), genericCollection(after.EnumValues)},
+	}
+
+	for _, collection := range collections {
+		fqns := make([]string, 0, len(collection.before)+len(collection.after))
+		for fqn := range collection.before {
+			fqns = append(fqns, fqn)
+		}
+		for fqn := range collection.after {
+			if _, found := collection.before[fqn]; !found {
+				fqns = append(fqns, fqn)
+			}
+		}
+		sort.Strings(fqns)
+
+		for _, fqn := range fqns {
+			beforeObject, inBefore := collection.before[fqn]
+			afterObject, inAfter := collection.after[fqn]
+
+			entry := &DiffEntry{Kind: collection.kind, FQN: fqn, File: diffFile(collection.kind, fqn, after, before)}
+			switch {
+			case !inBefore:
+				diff.Added = append(diff.Added, entry)
+			case !inAfter:
+				diff.Removed = append(diff.Removed, entry)
+			default:
+				changes, err := diffObjects(collection.kind, beforeObject, afterObject)
+				if err != nil {
+					return nil, fmt.Errorf("failed to compare %s: %w", fqn, err)
+				}
+				if len(changes) > 0 {
+					entry.Changes = changes
+					diff.Modified = append(diff.Modified, entry)
+				}
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// genericCollection converts a collection to a map of interface{}, so collections of any type can be compared alike
+func genericCollection[T any](collection map[string]*T) map[string]interface{} {
+	ret := make(map[string]interface{}, len(collection))
+	for key, value := range collection {
+		ret[key] = value
+	}
+	return ret
+}
+
+// diffFile returns the file an object is declared in, preferring the newer context
+func diffFile(kind string, fqn string, contexts ...*Context) string {
+	if kind == "file" {
+		return fqn
+	}
+	for _, ctx := range contexts {
+		if entry, found := ctx.Index[fqn]; found {
+			return entry.File
+		}
+	}
+	return ""
+}
+
+// diffObjects compares the JSON encodings of two objects of the same kind, key by key
+func diffObjects(kind string, before interface{}, after interface{}) ([]*DiffChange, error) {
+	beforeKeys, err := diffKeys(kind, before)
+	if err != nil {
+		return nil, err
+	}
+	afterKeys, err := diffKeys(kind, after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(beforeKeys)+len(afterKeys))
+	for key := range beforeKeys {
+		keys = append(keys, key)
+	}
+	for key := range afterKeys {
+		if _, found := beforeKeys[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*DiffChange, 0)
+	for _, key := range keys {
+		if !reflect.DeepEqual(beforeKeys[key], afterKeys[key]) {
+			changes = append(changes, &DiffChange{Key: key, Before: beforeKeys[key], After: afterKeys[key]})
+		}
+	}
+	return changes, nil
+}
+
+// diffKeys returns the compared keys of an object's JSON encoding, with each option as a separate key
+func diffKeys(kind string, object interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	if err := json.Unmarshal(encoded, &keys); err != nil {
+		return nil, err
+	}
+
+	// Declaration order isn't a semantic change
+	delete(keys, "declaration_index")
+	for _, key := range diffIgnoredKeys[kind] {
+		delete(keys, key)
+	}
+	for _, key := range diffDerivedKeys[kind] {
+		delete(keys, key)
+	}
+
+	if options, isObject := keys["options"].(map[string]interface{}); isObject {
+		delete(keys, "options")
+		for name, value := range options {
+			keys["options."+name] = value
+		}
+	}
+
+	return keys, nil
+}
+
+// WriteText writes the diff in a human-readable form, for changelogs
+func (d *SemanticDiff) WriteText(w io.Writer) error {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", d.Old, d.New)
+
+	sections := []struct {
+		title   string
+		marker  string
+		entries []*DiffEntry
+	}{
+		{"Added", "+", d.Added},
+		{"Removed", "-", d.Removed},
+		{"Modified", "~", d.Modified},
+	}
+
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "\n%s (%d)\n", section.title, len(section.entries))
+		for _, entry := range section.entries {
+			fmt.Fprintf(buf, "  %s %s %s\n", section.marker, strings.ReplaceAll(entry.Kind, "_", " "), entry.FQN)
+			for _, change := range entry.Changes {
+				fmt.Fprintf(buf, "      %s: %s -> %s\n", change.Key, diffValue(change.Before), diffValue(change.After))
+			}
+		}
+	}
+
+	if len(d.Added)+len(d.Removed)+len(d.Modified) == 0 {
+		buf.WriteString("\nNo changes\n")
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// diffValue formats a value of a changed key on a single line, or `(none)` if the key wasn't set
+func diffValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,96 @@
+package protojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// readFixtureOutput generates the `json` output of a fixture request, and reads it back
+func readFixtureOutput(t *testing.T, req *pluginpb.CodeGeneratorRequest) *Context {
+	t.Helper()
+
+	ctx, err := ReadContext(strings.NewReader(generateFixture(t, req)["output.json"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+// diffSummary lists the FQNs of diff entries, with the keys which changed
+func diffSummary(entries []*DiffEntry) []string {
+	ret := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		summary := entry.Kind + " " + strings.TrimPrefix(entry.FQN, fixturePackage)
+		for _, change := range entry.Changes {
+			summary += " " + change.Key
+		}
+		ret = append(ret, summary)
+	}
+	return ret
+}
+
+func TestDiff(t *testing.T) {
+	before := readFixtureOutput(t, fixtureRequest(t, "collections=sorted,examples=false"))
+
+	// The same request in another layout, with the derived examples, has no changes
+	unchanged := readFixtureOutput(t, fixtureRequest(t, "collections=array,examples=true"))
+	diff, err := Diff(before, unchanged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Modified) > 0 {
+		t.Errorf("got changes between layouts: %q %q %q", diffSummary(diff.Added), diffSummary(diff.Removed), diffSummary(diff.Modified))
+	}
+
+	req := fixtureRequest(t, "collections=array,examples=true")
+	todo := fixtureFile(t, req, "todo.proto")
+	messages := make([]*descriptorpb.DescriptorProto, 0)
+	for _, message := range todo.MessageType {
+		switch message.GetName() {
+		case "AddItemResponse":
+			continue
+		case "List":
+			message = proto.Clone(message).(*descriptorpb.DescriptorProto)
+			// Renumbering `id` is declared; `List` becoming recursive is derived
+			message.Field[0].Number = proto.Int32(10)
+			message.Field[1].Options.Deprecated = proto.Bool(true)
+			message.Field = append(message.Field, testMessage("", [2]string{"parent", "List"}).Field[0])
+			message.Field[len(message.Field)-1].Number = proto.Int32(11)
+		}
+		messages = append(messages, message)
+	}
+	todo.MessageType = append(messages, testMessage("Label", [2]string{"name", ""}))
+
+	diff, err = Diff(before, readFixtureOutput(t, req))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []*DiffEntry
+		want    []string
+	}{
+		{name: "added", entries: diff.Added, want: []string{"message Label", "field Label.name", "field List.parent"}},
+		{name: "removed", entries: diff.Removed, want: []string{"message AddItemResponse", "field AddItemResponse.item"}},
+		{name: "modified", entries: diff.Modified, want: []string{"field List.id number", "field List.name options.deprecated"}},
+	}
+	for _, test := range tests {
+		if got := diffSummary(test.entries); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %s %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	want := []*DiffChange{{Key: "options.deprecated", Before: nil, After: true}}
+	if len(diff.Modified) == 2 && !reflect.DeepEqual(diff.Modified[1].Changes, want) {
+		t.Errorf("got changes %+v, want %+v", diff.Modified[1].Changes, want)
+	}
+	if diff.Added[0].File != "todo.proto" || diff.Removed[0].File != "todo.proto" {
+		t.Errorf("got files %q and %q", diff.Added[0].File, diff.Removed[0].File)
+	}
+}
@@ -0,0 +1,198 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// Renderer renders a context into the files of an output format
+type Renderer interface {
+	Render(ctx *Context) ([]*plugin_go.CodeGeneratorResponse_File, error)
+}
+
+// NewRenderer returns the renderer for the output format selected in `params`
+func NewRenderer(params *Options) (Renderer, error) {
+	// A user-supplied template replaces the built-in formats
+	if len(params.Template) > 0 {
+		return newTemplateRenderer(params), nil
+	}
+
+	switch params.Format {
+	case "markdown":
+		return newMarkdownRenderer(params)
+	case "html":
+		return &htmlRenderer{}, nil
+	default:
+		encoder, err := newEncoder(params)
+		if err != nil {
+			return nil, err
+		}
+		return &documentRenderer{filename: params.Output, encoder: encoder}, nil
+	}
+}
+
+// Encoder writes the output document for a context, for formats which consist of a single document
+type Encoder interface {
+	Encode(w io.Writer, ctx *Context) error
+}
+
+// documentRenderer renders a context into a single document, using an Encoder
+type documentRenderer struct {
+	filename string
+	encoder  Encoder
+}
+
+func (r *documentRenderer) Render(ctx *Context) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	buf := new(bytes.Buffer)
+	if err := r.encoder.Encode(buf, ctx); err != nil {
+		return nil, err
+	}
+
+	return []*plugin_go.CodeGeneratorResponse_File{{
+		Name:    proto.String(r.filename),
+		Content: proto.String(buf.String()),
+	}}, nil
+}
+
+// newEncoder returns the encoder for the output format selected in `params`
+func newEncoder(params *Options) (Encoder, error) {
+	switch params.Format {
+	case "json":
+		return &jsonEncoder{indent: params.Indent, layout: params.Collections, version: params.SchemaVersion}, nil
+	case "ndjson":
+		return &ndjsonEncoder{version: params.SchemaVersion}, nil
+	case "yaml":
+		return &yamlEncoder{indent: yamlIndent(params.Indent), layout: params.Collections, version: params.SchemaVersion}, nil
+	case "dot":
+		return &graphEncoder{opts: newGraphOptions(params), write: writeDOT}, nil
+	case "mermaid":
+		return &graphEncoder{opts: newGraphOptions(params), write: writeMermaid}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", params.Format)
+	}
+}
+
+// jsonEncoder writes the context as a single JSON document.
+// If `indent` is empty, the output is compact.
+type jsonEncoder struct {
+	indent  string
+	layout  string
+	version string
+}
+
+func (e *jsonEncoder) Encode(w io.Writer, ctx *Context) error {
+	document, err := outputDocument(ctx, e.layout, e.version)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", e.indent)
+	return encoder.Encode(document)
+}
+
+// ndjsonEncoder writes newline-delimited JSON, with one record per line for every object in the context.
+// Each record contains the object's `kind` (as in `IndexEntry.Type`, or "file"), its `fqn`, the `file` and `parent`
+// from its index entry, and then the object's own fields. The first record is the metadata header, of kind "meta".
+// Records are downgraded to the output format `version`, like the other document formats (see `outputDocument`).
+type ndjsonEncoder struct {
+	version string
+}
+
+// ndjsonHeader is the start of each NDJSON record; the object itself is spliced in after it
+type ndjsonHeader struct {
+	Kind   string `json:"kind"`
+	FQN    string `json:"fqn,omitempty"`
+	File   string `json:"file,omitempty"`
+	Parent string `json:"parent,omitempty"`
+}
+
+func (e *ndjsonEncoder) Encode(w io.Writer, ctx *Context) error {
+	removed := keysAddedAfter(e.version)
+
+	// Older formats without a header don't get one, and newer ones describe the version written
+	if ctx.Meta != nil && !containsString(removed[""], "meta") {
+		meta := *ctx.Meta
+		meta.SchemaVersion = e.version
+		if err := writeNDJSONRecord(w, &ndjsonHeader{Kind: "meta"}, &meta); err != nil {
+			return err
+		}
+	}
+
+	order := ctx.declaredOrder()
+
+	for _, name := range order.Files {
+		file, err := downgradeObject(ctx.Files[name], removed["files"])
+		if err != nil {
+			return err
+		}
+		if err := writeNDJSONRecord(w, &ndjsonHeader{Kind: "file", FQN: name, File: name}, file); err != nil {
+			return err
+		}
+	}
+
+	collections := []struct {
+		name   string
+		keys   []string
+		lookup func(fqn string) interface{}
+	}{
+		{"services", order.Services, func(fqn string) interface{} { return ctx.Services[fqn] }},
+		{"methods", order.Methods, func(fqn string) interface{} { return ctx.Methods[fqn] }},
+		{"messages", order.Messages, func(fqn string) interface{} { return ctx.Messages[fqn] }},
+		{"fields", order.Fields, func(fqn string) interface{} { return ctx.Fields[fqn] }},
+		{"enums", order.Enums, func(fqn string) interface{} { return ctx.Enums[fqn] }},
+		{"enum_values", order.EnumValues, func(fqn string) interface{} { return ctx.EnumValues[fqn] }},
+	}
+
+	for _, collection := range collections {
+		for _, fqn := range collection.keys {
+			header := &ndjsonHeader{FQN: fqn}
+			if entry, found := ctx.Index[fqn]; found {
+				header.Kind = entry.Type
+				header.File = entry.File
+				header.Parent = entry.Parent
+			}
+
+			object, err := downgradeObject(collection.lookup(fqn), removed[collection.name])
+			if err != nil {
+				return err
+			}
+			if err := writeNDJSONRecord(w, header, object); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeNDJSONRecord writes a single line containing the fields of `header`, followed by the fields of `object`
+func writeNDJSONRecord(w io.Writer, header *ndjsonHeader, object interface{}) error {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	encodedObject, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	// Splice the two objects together: drop the closing brace of the header and the opening brace of the object
+	line := new(bytes.Buffer)
+	line.Write(encodedHeader[:len(encodedHeader)-1])
+	if len(encodedObject) > 2 {
+		line.WriteByte(',')
+		line.Write(encodedObject[1:])
+	} else {
+		line.WriteByte('}')
+	}
+	line.WriteByte('\n')
+
+	_, err = w.Write(line.Bytes())
+	return err
+}
@@ -0,0 +1,91 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNDJSON(t *testing.T) {
+	for _, indent := range []string{"0", "4", "tab"} {
+		t.Run("indent "+indent, func(t *testing.T) {
+			req := fixtureRequest(t, "format=ndjson,indent="+indent)
+			output := generateFixture(t, req)["output.json"]
+			ctx := buildFixture(t, fixtureRequest(t, ""))
+
+			if !strings.HasSuffix(output, "}\n") {
+				t.Errorf("the output doesn't end with a complete line")
+			}
+
+			// Each line is a single compact JSON object
+			kinds := make([]string, 0)
+			fqns := make([]string, 0)
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+				compact := new(bytes.Buffer)
+				if err := json.Compact(compact, []byte(line)); err != nil {
+					t.Fatalf("%q isn't valid JSON: %v", line, err)
+				}
+				if compact.String() != line {
+					t.Errorf("%q isn't compact", line)
+				}
+
+				var record map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("%q isn't an object: %v", line, err)
+				}
+				kind, _ := record["kind"].(string)
+				fqn, _ := record["fqn"].(string)
+				kinds = append(kinds, kind)
+				fqns = append(fqns, fqn)
+
+				// Each record has the object's own fields, and the file and parent of its index entry
+				switch kind {
+				case "meta":
+					if record["schema_version"] != OutputSchemaVersion {
+						t.Errorf("got meta %v", record)
+					}
+				case "file":
+					if record["name"] != fqn {
+						t.Errorf("got file %v", record)
+					}
+				default:
+					entry := ctx.Index[fqn]
+					if entry == nil || entry.Type != kind || record["full_name"] != fqn || record["file"] != entry.File {
+						t.Errorf("got record %v for index entry %+v", record, entry)
+					}
+					if parent, _ := record["parent"].(string); parent != entry.Parent {
+						t.Errorf("got parent %q for %s, want %q", parent, fqn, entry.Parent)
+					}
+				}
+			}
+
+			// The header comes first, then every collection in turn, each in declaration order
+			order := ctx.declaredOrder()
+			wantFQNs := []string{""}
+			wantKinds := []string{"meta"}
+			collections := []struct {
+				kind string
+				fqns []string
+			}{
+				{"file", order.Files},
+				{"service", order.Services},
+				{"method", order.Methods},
+				{"message", order.Messages},
+				{"field", order.Fields},
+				{"enum", order.Enums},
+				{"enum_value", order.EnumValues},
+			}
+			for _, collection := range collections {
+				for _, fqn := range collection.fqns {
+					wantKinds = append(wantKinds, collection.kind)
+					wantFQNs = append(wantFQNs, fqn)
+				}
+			}
+			if !reflect.DeepEqual(kinds, wantKinds) || !reflect.DeepEqual(fqns, wantFQNs) {
+				t.Errorf("got records %q %q, want %q %q", kinds, fqns, wantKinds, wantFQNs)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package protojson
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// exampleTag marks an example value in a comment, EG `@example "ada@example.com"`. The rest of the line is parsed as
+// JSON, or used as a string if it isn't valid JSON.
+const exampleTag = "@example"
+
+// scalarExample returns an example of the scalar type `protoType` in canonical proto3 JSON, or nil if it isn't a
+// scalar type. 64-bit integers are strings, and strings are the name of the field they're an example for.
+func scalarExample(protoType string, name string) interface{} {
+	switch protoType {
+	case "string":
+		if len(name) > 0 {
+			return name
+		}
+		return "string"
+	case "bytes":
+		// "bytes", base64-encoded
+		return "Ynl0ZXM="
+	case "bool":
+		return true
+	case "int32", "sint32", "sfixed32", "uint32", "fixed32":
+		return 0
+	case "int64", "sint64", "sfixed64", "uint64", "fixed64":
+		return "0"
+	case "double", "float":
+		return 0.0
+	default:
+		return nil
+	}
+}
+
+// exampleBuilder builds example canonical proto3 JSON instances of messages
+type exampleBuilder struct {
+	ctx *Context
+	// option is the FQN of a custom option which overrides the examples of fields and messages, if any
+	option string
+	// cache holds the examples which don't depend on where their message is used, by FQN; if nil, nothing is cached
+	cache map[string]*cachedExample
+	// cutDepth is the shallowest depth of `visiting` at which a recursive field has been left out, since it was reset
+	cutDepth int
+	// expanded are the messages which have been built, since it was reset
+	expanded map[string]bool
+}
+
+// cachedExample is an example of a message, with every message built for it
+type cachedExample struct {
+	example  interface{}
+	expanded map[string]bool
+}
+
+// generateExamples sets `Example` on every message (except map entries) in a context.
+// Messages share the examples of the messages they refer to, so they must not be modified.
+func generateExamples(ctx *Context, option string) {
+	builder := &exampleBuilder{ctx: ctx, option: option, cache: make(map[string]*cachedExample)}
+	for _, message := range ctx.Messages {
+		if !message.IsMapEntry {
+			message.Example = builder.messageExample(message, make(map[string]int))
+		}
+	}
+}
+
+// override returns the example set on an object with the custom option or an `@example` comment tag, if any.
+// The option takes precedence, since it is explicitly configured.
+func (b *exampleBuilder) override(description string, options map[string]interface{}) (interface{}, bool) {
+	if len(b.option) > 0 {
+		if value, found := options[b.option]; found {
+			if str, isString := value.(string); isString {
+				return parseExample(str), true
+			}
+			return value, true
+		}
+	}
+
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, exampleTag) {
+			return parseExample(strings.TrimSpace(strings.TrimPrefix(line, exampleTag))), true
+		}
+	}
+
+	return nil, false
+}
+
+// parseExample parses an example value as JSON, falling back to the raw string
+func parseExample(str string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(str), &value); err != nil {
+		return str
+	}
+	return value
+}
+
+// messageExample builds an example of `message`. `visiting` holds the messages currently being built, with their
+// depth, so recursive messages end instead of looping forever: fields which would recurse into them are left out.
+//
+// An example only depends on where its message is used if a field was left out for recursing into a message which
+// was being built before it. Otherwise it is cached, so messages which are referenced many times are only built once,
+// and reused wherever none of the messages built for it are being built.
+func (b *exampleBuilder) messageExample(message *Message, visiting map[string]int) interface{} {
+	if example, found := b.override(message.Description, message.Options); found {
+		return example
+	}
+	if wellKnown, isWellKnown := wellKnownTypes[message.FullName]; isWellKnown {
+		return wellKnown.example()
+	}
+	if cached, found := b.cache[message.FullName]; found && !anyVisiting(cached.expanded, visiting) {
+		if b.expanded != nil {
+			for fqn := range cached.expanded {
+				b.expanded[fqn] = true
+			}
+		}
+		return cached.example
+	}
+
+	depth := len(visiting) + 1
+	visiting[message.FullName] = depth
+	outerCutDepth, outerExpanded := b.cutDepth, b.expanded
+	b.cutDepth, b.expanded = math.MaxInt, map[string]bool{message.FullName: true}
+	defer func() {
+		delete(visiting, message.FullName)
+		if outerCutDepth < b.cutDepth {
+			b.cutDepth = outerCutDepth
+		}
+		if outerExpanded != nil {
+			for fqn := range b.expanded {
+				outerExpanded[fqn] = true
+			}
+		}
+		b.expanded = outerExpanded
+	}()
+
+	ret := newOrderedMap()
+	chosenOneofs := make(map[string]bool)
+	for _, fqn := range message.Fields {
+		field := b.ctx.Fields[fqn]
+
+		// Only the first member of a oneof can be set
+		if len(field.Oneof) > 0 {
+			if chosenOneofs[field.Oneof] {
+				continue
+			}
+			chosenOneofs[field.Oneof] = true
+		}
+
+		if example, found := b.fieldExample(field, visiting); found {
+			ret.Set(jsonFieldName(field), example)
+		}
+	}
+
+	if b.cache != nil && b.cutDepth >= depth {
+		b.cache[message.FullName] = &cachedExample{example: ret, expanded: b.expanded}
+	}
+	return ret
+}
+
+// anyVisiting reports whether any of the messages in `expanded` are being built
+func anyVisiting(expanded map[string]bool, visiting map[string]int) bool {
+	for fqn := range visiting {
+		if expanded[fqn] {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldExample builds an example value of a field, reporting false if the field should be left out
+func (b *exampleBuilder) fieldExample(field *Field, visiting map[string]int) (interface{}, bool) {
+	if example, found := b.override(field.Description, field.Options); found {
+		return example, true
+	}
+
+	// Maps are objects with a single example entry
+	if entry, found := b.ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+		key, value := b.ctx.Fields[entry.Fields[0]], b.ctx.Fields[entry.Fields[1]]
+		ret := newOrderedMap()
+		if example, found := b.typeExample(value, visiting); found {
+			ret.Set(mapKeyExample(key.FullType), example)
+		}
+		return ret, true
+	}
+
+	example, found := b.typeExample(field, visiting)
+	if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED.String() {
+		if !found {
+			return []interface{}{}, true
+		}
+		return []interface{}{example}, true
+	}
+	return example, found
+}
+
+// typeExample builds an example value of the type of a field, ignoring its cardinality
+func (b *exampleBuilder) typeExample(field *Field, visiting map[string]int) (interface{}, bool) {
+	if example := scalarExample(field.FullType, field.Name); example != nil {
+		return example, true
+	}
+
+	if message, found := b.ctx.Messages[field.FullType]; found {
+		if depth, found := visiting[message.FullName]; found {
+			if depth < b.cutDepth {
+				b.cutDepth = depth
+			}
+			return nil, false
+		}
+		return b.messageExample(message, visiting), true
+	}
+	if wellKnown, isWellKnown := wellKnownTypes[field.FullType]; isWellKnown {
+		return wellKnown.example(), true
+	}
+
+	if enum, found := b.ctx.Enums[field.FullType]; found && len(enum.Values) > 0 {
+		return b.ctx.EnumValues[enum.Values[0]].Name, true
+	}
+
+	// Enums and messages which aren't in the context can only be described generically
+	if field.Descriptor != nil && field.Descriptor.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+		return 0, true
+	}
+	return newOrderedMap(), true
+}
+
+// mapKeyExample returns an example map key of the scalar type `protoType`. Keys are always strings in JSON.
+func mapKeyExample(protoType string) string {
+	switch protoType {
+	case "string":
+		return "key"
+	case "bool":
+		return "true"
+	default:
+		return "0"
+	}
+}
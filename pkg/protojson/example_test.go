@@ -0,0 +1,101 @@
+package protojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// testMessage builds a message descriptor whose fields refer to the messages `fields` (by field name) of the fixture
+// package, or are strings if the type is empty
+func testMessage(name string, fields ...[2]string) *descriptorpb.DescriptorProto {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for i, field := range fields {
+		descriptor := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(field[0]),
+			JsonName: proto.String(field[0]),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+		if len(field[1]) > 0 {
+			descriptor.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			descriptor.TypeName = proto.String(".com.pseudomuto.protokit.v1." + field[1])
+		}
+		message.Field = append(message.Field, descriptor)
+	}
+	return message
+}
+
+// addTestMessages adds messages to the todo.proto fixture
+func addTestMessages(t testing.TB, req *pluginpb.CodeGeneratorRequest, messages ...*descriptorpb.DescriptorProto) {
+	file := fixtureFile(t, req, "todo.proto")
+	file.MessageType = append(file.MessageType, messages...)
+}
+
+func TestMessageExampleCacheMatchesUncached(t *testing.T) {
+	req := fixtureRequest(t, "")
+	// Cycles of different lengths, entered from different places
+	addTestMessages(t, req,
+		testMessage("Node", [2]string{"child", "Node"}, [2]string{"tree", "Tree"}),
+		testMessage("Tree", [2]string{"root", "Node"}, [2]string{"leaf", "Leaf"}),
+		testMessage("Leaf", [2]string{"name", ""}, [2]string{"tree", "Tree"}, [2]string{"node", "Node"}),
+		testMessage("Forest", [2]string{"tree", "Tree"}, [2]string{"leaf", "Leaf"}),
+	)
+	ctx := buildFixture(t, req)
+
+	encode := func(example interface{}) string {
+		encoded, err := json.Marshal(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(encoded)
+	}
+
+	// The messages are built in a different order each time, so the cache is filled differently
+	for i := 0; i < 20; i++ {
+		uncached := &exampleBuilder{ctx: ctx}
+		generateExamples(ctx, "")
+
+		for fqn, message := range ctx.Messages {
+			if message.IsMapEntry {
+				continue
+			}
+			want := encode(uncached.messageExample(message, make(map[string]int)))
+			if got := encode(message.Example); got != want {
+				t.Fatalf("the example of %s is %s, want %s", fqn, got, want)
+			}
+		}
+	}
+}
+
+func TestMessageExampleDiamonds(t *testing.T) {
+	req := fixtureRequest(t, "examples=true")
+	// Each level refers to the next twice, so expanding every reference separately would take 2^40 messages
+	const levels = 40
+	for i := 0; i < levels; i++ {
+		next := fmt.Sprintf("Level%d", i+1)
+		addTestMessages(t, req, testMessage(fmt.Sprintf("Level%d", i), [2]string{"left", next}, [2]string{"right", next}))
+	}
+	addTestMessages(t, req, testMessage(fmt.Sprintf("Level%d", levels), [2]string{"name", ""}))
+
+	ctx := buildFixture(t, req)
+
+	example := ctx.Messages["com.pseudomuto.protokit.v1.Level0"].Example
+	for i := 0; i < levels; i++ {
+		object, isObject := example.(*orderedMap)
+		if !isObject {
+			t.Fatalf("level %d of the example is %v, want an object", i, example)
+		}
+		example, _ = object.Get("right")
+	}
+	if object, isObject := example.(*orderedMap); !isObject {
+		t.Errorf("the last level of the example is %v, want an object", example)
+	} else if name, _ := object.Get("name"); name != "name" {
+		t.Errorf("the last level of the example has name %v, want \"name\"", name)
+	}
+}
@@ -0,0 +1,82 @@
+package protojson
+
+import (
+	"fmt"
+
+	"github.com/pseudomuto/protokit"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// addExternalTypes indexes a stub entry for every message and enum which a field or method refers to, but which is
+// declared in a file that isn't being generated (EG an import), and for the messages it is nested in. Stubs are
+// marked `External`, with the file which declares the type and its comments, so every reference in the output
+// resolves. They aren't added to any collection, since their fields and values aren't known.
+func addExternalTypes(ctx *Context, protoFiles []*descriptorpb.FileDescriptorProto) {
+	referenced := make(map[string]bool)
+	for _, field := range ctx.Fields {
+		referenced[field.FullType] = true
+	}
+	for _, method := range ctx.Methods {
+		referenced[method.InputType] = true
+		referenced[method.OutputType] = true
+	}
+
+	declarations := externalDeclarations(protoFiles)
+
+	for fqn := range declarations {
+		if !referenced[fqn] {
+			continue
+		}
+
+		// The messages a stub is nested in are indexed too, so its parent resolves
+		for entry, found := declarations[fqn]; found; entry, found = declarations[fqn] {
+			if _, indexed := ctx.Index[fqn]; indexed {
+				break
+			}
+			ctx.Index[fqn] = entry
+			fqn = entry.Parent
+		}
+	}
+}
+
+// externalDeclarations builds external index entries for every message and enum declared in `protoFiles`, by FQN
+func externalDeclarations(protoFiles []*descriptorpb.FileDescriptorProto) map[string]*IndexEntry {
+	ret := make(map[string]*IndexEntry)
+
+	for _, file := range protoFiles {
+		comments := protokit.ParseComments(file)
+		declare := func(kind string, fqn string, parent string, path string) {
+			entry := &IndexEntry{Type: kind, File: file.GetName(), Parent: parent, External: true}
+			if comment, found := comments[path]; found {
+				entry.Description = comment.String()
+			}
+			ret[fqn] = entry
+		}
+
+		prefix := ""
+		if len(file.GetPackage()) > 0 {
+			prefix = file.GetPackage() + "."
+		}
+
+		var declareMessages func(messages []*descriptorpb.DescriptorProto, scope string, parent string, path string)
+		declareMessages = func(messages []*descriptorpb.DescriptorProto, scope string, parent string, path string) {
+			for i, message := range messages {
+				fqn := scope + message.GetName()
+				messagePath := fmt.Sprintf("%s.%d", path, i)
+				declare("message", fqn, parent, messagePath)
+
+				declareMessages(message.GetNestedType(), fqn+".", fqn, fmt.Sprintf("%s.%d", messagePath, messageNestedPath))
+				for j, enum := range message.GetEnumType() {
+					declare("enum", fqn+"."+enum.GetName(), fqn, fmt.Sprintf("%s.%d.%d", messagePath, messageEnumPath, j))
+				}
+			}
+		}
+
+		declareMessages(file.GetMessageType(), prefix, "", fmt.Sprint(fileMessagePath))
+		for i, enum := range file.GetEnumType() {
+			declare("enum", prefix+enum.GetName(), "", fmt.Sprintf("%d.%d", fileEnumPath, i))
+		}
+	}
+
+	return ret
+}
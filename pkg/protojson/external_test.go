@@ -0,0 +1,52 @@
+package protojson
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestExternalTypes(t *testing.T) {
+	req := fixtureRequest(t, "externals=true", "booking.proto")
+	// Refer to a type nested in a message which nothing else refers to
+	field := fixtureFile(t, req, "booking.proto").GetMessageType()[1].GetField()[2]
+	field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	field.TypeName = proto.String(".com.pseudomuto.protokit.v1.CreateListResponse.Status")
+
+	ctx := buildFixture(t, req)
+
+	tests := []struct {
+		fqn    string
+		kind   string
+		parent string
+	}{
+		{"com.pseudomuto.protokit.v1.CreateListResponse.Status", "message", "com.pseudomuto.protokit.v1.CreateListResponse"},
+		{"com.pseudomuto.protokit.v1.CreateListResponse", "message", ""},
+	}
+	for _, test := range tests {
+		entry, found := ctx.Index[test.fqn]
+		if !found {
+			t.Errorf("%s is not indexed", test.fqn)
+			continue
+		}
+		if !entry.External || entry.Type != test.kind || entry.Parent != test.parent || entry.File != "todo.proto" {
+			t.Errorf("%s is indexed as %+v, want an external %s in todo.proto with parent %q", test.fqn, entry, test.kind, test.parent)
+		}
+	}
+
+	// Every parent in the index resolves, and types declared in generated files aren't stubs
+	for fqn, entry := range ctx.Index {
+		if _, found := ctx.Index[entry.Parent]; len(entry.Parent) > 0 && !found {
+			t.Errorf("the parent %s of %s is not indexed", entry.Parent, fqn)
+		}
+		if entry.External && entry.File == "booking.proto" {
+			t.Errorf("%s is declared in a generated file, but indexed as external", fqn)
+		}
+	}
+
+	// Only referenced types are stubbed
+	if _, found := ctx.Index["com.pseudomuto.protokit.v1.List"]; found {
+		t.Errorf("com.pseudomuto.protokit.v1.List is indexed, but nothing refers to it")
+	}
+}
@@ -0,0 +1,86 @@
+package protojson
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// fixtureFiles are the files of testdata/fileset.pb, the test fixtures of protokit, built with their imports
+var fixtureFiles = []string{"booking.proto", "extend.proto", "todo.proto", "todo_import.proto"}
+
+// fixtureRequest builds the request `protoc` would send for the fixture files `generate` (or all of them), with the
+// plugin parameter `parameter`
+func fixtureRequest(t testing.TB, parameter string, generate ...string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "fileset.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(data, set); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(generate) == 0 {
+		generate = append([]string{}, fixtureFiles...)
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: generate,
+		Parameter:      proto.String(parameter),
+		ProtoFile:      set.GetFile(),
+	}
+}
+
+// fixtureFile returns the descriptor of a file in a fixture request, to modify it
+func fixtureFile(t testing.TB, req *pluginpb.CodeGeneratorRequest, name string) *descriptorpb.FileDescriptorProto {
+	t.Helper()
+
+	for _, file := range req.GetProtoFile() {
+		if file.GetName() == name {
+			return file
+		}
+	}
+	t.Fatalf("%s is not a fixture file", name)
+	return nil
+}
+
+// buildFixture builds the context of a fixture request, with the options in its parameter
+func buildFixture(t testing.TB, req *pluginpb.CodeGeneratorRequest) *Context {
+	t.Helper()
+
+	opts, err := ParseOptions(req.GetParameter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := Build(req, *opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+// generateFixture runs the plugin on a fixture request, failing the test if it reports an error, and returns the
+// content of the files it writes by name
+func generateFixture(t testing.TB, req *pluginpb.CodeGeneratorRequest) map[string]string {
+	t.Helper()
+
+	resp, err := Generate(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+
+	files := make(map[string]string)
+	for _, file := range resp.GetFile() {
+		files[file.GetName()] = file.GetContent()
+	}
+	return files
+}
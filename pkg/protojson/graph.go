@@ -0,0 +1,433 @@
+package protojson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TypeGraph is the reference graph of the services, methods, messages and enums in a context, for diagrams
+type TypeGraph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+// GraphNode is a service, method, message or enum in a TypeGraph
+type GraphNode struct {
+	// ID is the FQN of the object
+	ID string `json:"id"`
+	// Kind is the type of the object, as in `IndexEntry.Type`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	// Members are the fields of a message or the values of an enum
+	Members []*GraphMember `json:"members,omitempty"`
+}
+
+// GraphMember is a field of a message node (with its type) or a value of an enum node (without one)
+type GraphMember struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// GraphEdge is a reference from one node to another
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// graphOptions scope and simplify a TypeGraph
+type graphOptions struct {
+	// Package restricts the graph to the objects declared in a package
+	Package string
+	// Service restricts the graph to a service, its methods, and the types reachable from them
+	Service string
+	// CollapseNested merges nested messages and enums into the top-level message they're declared in
+	CollapseNested bool
+	// Root restricts the graph to an object and the types reachable from it
+	Root string
+}
+
+func newGraphOptions(params *Options) *graphOptions {
+	return &graphOptions{
+		Package:        params.GraphPackage,
+		Service:        params.GraphService,
+		CollapseNested: params.GraphCollapseNested,
+	}
+}
+
+// validate checks that the objects which scope the graph are defined in a context
+func (opts *graphOptions) validate(ctx *Context) error {
+	if _, found := ctx.Services[opts.Service]; len(opts.Service) > 0 && !found {
+		return fmt.Errorf("graph_service %q is not a service defined in the generated files", opts.Service)
+	}
+	if _, found := ctx.Lookup(opts.Root); len(opts.Root) > 0 && !found {
+		return fmt.Errorf("%q is not defined in the generated files", opts.Root)
+	}
+	if len(opts.Package) > 0 && !containsString(ctx.packages(), opts.Package) {
+		return fmt.Errorf("graph_package %q is not defined in the generated files", opts.Package)
+	}
+	return nil
+}
+
+// TypeGraphOf builds the reference graph of the object `root` and the types reachable from it, or of the whole context
+// if `root` is empty (see `buildTypeGraph`)
+func TypeGraphOf(ctx *Context, root string) (*TypeGraph, error) {
+	return buildTypeGraph(ctx, &graphOptions{Root: root})
+}
+
+// buildTypeGraph builds the reference graph of a context. Edges are added from services to their methods, from
+// methods to their request and response types, and from messages to the messages and enums their fields refer to.
+// References to types outside the graph's scope (or the context) are left out.
+func buildTypeGraph(ctx *Context, opts *graphOptions) (*TypeGraph, error) {
+	if err := opts.validate(ctx); err != nil {
+		return nil, err
+	}
+
+	inScope := func(fqn string) bool { return true }
+	if len(opts.Service) > 0 {
+		reachable, err := reachableFrom(ctx, []string{opts.Service})
+		if err != nil {
+			return nil, err
+		}
+		inScope = func(fqn string) bool { return reachable[fqn] }
+	}
+	if len(opts.Root) > 0 {
+		reachable := reachableObjects(ctx, []string{opts.Root})
+		withinService := inScope
+		inScope = func(fqn string) bool { return reachable[fqn] && withinService(fqn) }
+	}
+	if len(opts.Package) > 0 {
+		withinService := inScope
+		inScope = func(fqn string) bool {
+			entry, found := ctx.Index[fqn]
+			if !found || entry.External {
+				return false
+			}
+			return ctx.Files[entry.File].Package == opts.Package && withinService(fqn)
+		}
+	}
+
+	// nodeOf returns the node which represents the object `fqn`, which is its outermost message if collapsing
+	nodeOf := func(fqn string) string {
+		if !opts.CollapseNested {
+			return fqn
+		}
+		for {
+			entry, found := ctx.Index[fqn]
+			if !found || len(entry.Parent) == 0 {
+				return fqn
+			}
+			fqn = entry.Parent
+		}
+	}
+
+	// drawn reports whether the message or enum `fqn` has a node. When collapsing, a top-level message has one if it
+	// or any type nested in it is in scope.
+	drawn := inScope
+	if opts.CollapseNested {
+		outermost := make(map[string]bool)
+		for fqn, message := range ctx.Messages {
+			if inScope(fqn) && !message.IsMapEntry {
+				outermost[nodeOf(fqn)] = true
+			}
+		}
+		for fqn := range ctx.Enums {
+			if inScope(fqn) {
+				outermost[nodeOf(fqn)] = true
+			}
+		}
+		drawn = func(fqn string) bool { return outermost[fqn] }
+	}
+
+	// nestedMembers lists the fields and values of the in-scope types nested in a message, prefixed with their names
+	// within it, EG `Status.code`
+	var nestedMembers func(message *Message, prefix string) []*GraphMember
+	nestedMembers = func(message *Message, prefix string) []*GraphMember {
+		ret := make([]*GraphMember, 0)
+		for _, nestedName := range message.Messages {
+			nested, found := ctx.Messages[nestedName]
+			if !found || nested.IsMapEntry {
+				continue
+			}
+			if inScope(nestedName) {
+				for _, fieldName := range nested.Fields {
+					field := ctx.Fields[fieldName]
+					ret = append(ret, &GraphMember{Name: prefix + nested.Name + "." + field.Name, Type: graphFieldType(ctx, field)})
+				}
+			}
+			ret = append(ret, nestedMembers(nested, prefix+nested.Name+".")...)
+		}
+		for _, enumName := range message.Enums {
+			if enum, found := ctx.Enums[enumName]; found && inScope(enumName) {
+				for _, valueName := range enum.Values {
+					ret = append(ret, &GraphMember{Name: prefix + enum.Name + "." + ctx.EnumValues[valueName].Name})
+				}
+			}
+		}
+		return ret
+	}
+
+	graph := &TypeGraph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0)}
+	nodes := make(map[string]*GraphNode)
+	addNode := func(node *GraphNode) {
+		graph.Nodes = append(graph.Nodes, node)
+		nodes[node.ID] = node
+	}
+
+	seenEdges := make(map[GraphEdge]bool)
+	addEdge := func(from string, to string, label string) {
+		edge := GraphEdge{From: from, To: to, Label: label}
+		if _, found := nodes[to]; found && !seenEdges[edge] {
+			seenEdges[edge] = true
+			graph.Edges = append(graph.Edges, &edge)
+		}
+	}
+
+	order := ctx.declaredOrder()
+
+	for _, fqn := range order.Services {
+		if inScope(fqn) {
+			addNode(&GraphNode{ID: fqn, Kind: "service", Label: ctx.localName(fqn)})
+		}
+	}
+	for _, fqn := range order.Methods {
+		if inScope(fqn) {
+			addNode(&GraphNode{ID: fqn, Kind: "method", Label: ctx.localName(fqn)})
+		}
+	}
+	for _, fqn := range order.Messages {
+		if message := ctx.Messages[fqn]; drawn(fqn) && !message.IsMapEntry && nodeOf(fqn) == fqn {
+			node := &GraphNode{ID: fqn, Kind: "message", Label: ctx.localName(fqn)}
+			for _, fieldName := range message.Fields {
+				field := ctx.Fields[fieldName]
+				node.Members = append(node.Members, &GraphMember{Name: field.Name, Type: graphFieldType(ctx, field)})
+			}
+			if opts.CollapseNested {
+				node.Members = append(node.Members, nestedMembers(message, "")...)
+			}
+			addNode(node)
+		}
+	}
+	for _, fqn := range order.Enums {
+		if drawn(fqn) && nodeOf(fqn) == fqn {
+			node := &GraphNode{ID: fqn, Kind: "enum", Label: ctx.localName(fqn)}
+			for _, valueName := range ctx.Enums[fqn].Values {
+				node.Members = append(node.Members, &GraphMember{Name: ctx.EnumValues[valueName].Name})
+			}
+			addNode(node)
+		}
+	}
+
+	// Edges are only added once every node exists, so they can refer to nodes declared later
+	for _, fqn := range order.Services {
+		if inScope(fqn) {
+			for _, method := range ctx.Services[fqn].Methods {
+				addEdge(fqn, method, "")
+			}
+		}
+	}
+	for _, fqn := range order.Methods {
+		if method := ctx.Methods[fqn]; inScope(fqn) {
+			addEdge(fqn, nodeOf(method.InputType), streamingLabel("request", method.ClientStreaming))
+			addEdge(fqn, nodeOf(method.OutputType), streamingLabel("response", method.ServerStreaming))
+		}
+	}
+	for _, fqn := range order.Messages {
+		message := ctx.Messages[fqn]
+		if !inScope(fqn) || message.IsMapEntry {
+			continue
+		}
+
+		for _, fieldName := range message.Fields {
+			target := graphFieldTarget(ctx, ctx.Fields[fieldName])
+			if !inScope(target) {
+				continue
+			}
+
+			// Collapsing nested types can turn references between them into self-references, which aren't
+			// meaningful; genuine self-references are kept
+			from, to := nodeOf(fqn), nodeOf(target)
+			if from == to && fqn != target {
+				continue
+			}
+			addEdge(from, to, ctx.Fields[fieldName].Name)
+		}
+	}
+
+	return graph, nil
+}
+
+// graphFieldTarget returns the type a field refers to; for maps, this is the type of the values
+func graphFieldTarget(ctx *Context, field *Field) string {
+	if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+		return ctx.Fields[entry.Fields[1]].FullType
+	}
+	return field.FullType
+}
+
+// graphFieldType describes the type of a field, EG `Item[]` or `map<string, Item>`
+func graphFieldType(ctx *Context, field *Field) string {
+	if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+		key, value := ctx.Fields[entry.Fields[0]], ctx.Fields[entry.Fields[1]]
+		return fmt.Sprintf("map<%s, %s>", ctx.localName(key.FullType), ctx.localName(value.FullType))
+	}
+	if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED.String() {
+		return ctx.localName(field.FullType) + "[]"
+	}
+	return ctx.localName(field.FullType)
+}
+
+// streamingLabel returns the label of an edge from a method to its request or response
+func streamingLabel(label string, streaming bool) string {
+	if streaming {
+		return "stream " + label
+	}
+	return label
+}
+
+// graphEncoder writes the type graph of a context as a Graphviz DOT or Mermaid diagram
+type graphEncoder struct {
+	opts  *graphOptions
+	write func(w io.Writer, graph *TypeGraph) error
+}
+
+func (e *graphEncoder) Encode(w io.Writer, ctx *Context) error {
+	graph, err := buildTypeGraph(ctx, e.opts)
+	if err != nil {
+		return err
+	}
+	return e.write(w, graph)
+}
+
+// dotRecordSpecialChars are the characters which must be escaped in the labels of Graphviz record nodes
+var dotRecordSpecialChars = regexp.MustCompile(`[{}|<>"\\]`)
+
+// dotRecordEscape escapes text for use in the label of a Graphviz record node
+func dotRecordEscape(str string) string {
+	return dotRecordSpecialChars.ReplaceAllStringFunc(str, func(c string) string { return "\\" + c })
+}
+
+// writeDOT writes a graph as a Graphviz DOT digraph, with each service, message and enum as a record node
+func writeDOT(w io.Writer, graph *TypeGraph) error {
+	buf := new(strings.Builder)
+	buf.WriteString("digraph types {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=record, fontname=\"Helvetica\", fontsize=10];\n")
+	buf.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n\n")
+
+	for _, node := range graph.Nodes {
+		label := dotRecordEscape(node.Label)
+		if node.Kind != "message" {
+			label = dotRecordEscape("«"+node.Kind+"»") + "\\n" + label
+		}
+
+		if len(node.Members) > 0 {
+			members := make([]string, 0, len(node.Members))
+			for _, member := range node.Members {
+				if len(member.Type) > 0 {
+					members = append(members, dotRecordEscape(member.Name+": "+member.Type)+"\\l")
+				} else {
+					members = append(members, dotRecordEscape(member.Name)+"\\l")
+				}
+			}
+			label = "{" + label + "|" + strings.Join(members, "") + "}"
+		}
+
+		style := ""
+		switch node.Kind {
+		case "service":
+			style = ", style=filled, fillcolor=\"#dbe9f6\""
+		case "method":
+			style = ", shape=box, style=rounded"
+		case "enum":
+			style = ", style=filled, fillcolor=\"#f6f0db\""
+		}
+
+		fmt.Fprintf(buf, "  %q [label=\"%s\"%s];\n", node.ID, label, style)
+	}
+
+	if len(graph.Edges) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, edge := range graph.Edges {
+		if len(edge.Label) > 0 {
+			fmt.Fprintf(buf, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Label)
+		} else {
+			fmt.Fprintf(buf, "  %q -> %q;\n", edge.From, edge.To)
+		}
+	}
+
+	buf.WriteString("}\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// mermaidID converts an FQN to a Mermaid class ID, which may only contain letters, digits and underscores.
+// Underscores escape everything else, so distinct FQNs always have distinct IDs: `.` is written `__`, `_` is written
+// `_u`, and any other byte `b` is written `_x` followed by its two hex digits.
+func mermaidID(fqn string) string {
+	id := new(strings.Builder)
+	for i := 0; i < len(fqn); i++ {
+		switch c := fqn[i]; {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			id.WriteByte(c)
+		case c == '.':
+			id.WriteString("__")
+		case c == '_':
+			id.WriteString("_u")
+		default:
+			fmt.Fprintf(id, "_x%02x", c)
+		}
+	}
+	return id.String()
+}
+
+// mermaidText makes text safe to use in a Mermaid class member or label; generics are written with tildes
+func mermaidText(str string) string {
+	return strings.NewReplacer("<", "~", ">", "~", `"`, "'").Replace(str)
+}
+
+// writeMermaid writes a graph as a Mermaid `classDiagram`, with each service, method, message and enum as a class
+func writeMermaid(w io.Writer, graph *TypeGraph) error {
+	buf := new(strings.Builder)
+	buf.WriteString("classDiagram\n")
+
+	for _, node := range graph.Nodes {
+		body := make([]string, 0, len(node.Members)+1)
+		switch node.Kind {
+		case "service", "method":
+			body = append(body, "<<"+node.Kind+">>")
+		case "enum":
+			body = append(body, "<<enumeration>>")
+		}
+		for _, member := range node.Members {
+			if len(member.Type) > 0 {
+				body = append(body, mermaidText(member.Type)+" "+member.Name)
+			} else {
+				body = append(body, member.Name)
+			}
+		}
+
+		// Classes without a body are declared without braces, which Mermaid requires
+		fmt.Fprintf(buf, "  class %s[\"%s\"]", mermaidID(node.ID), mermaidText(node.Label))
+		if len(body) > 0 {
+			buf.WriteString(" {\n    " + strings.Join(body, "\n    ") + "\n  }")
+		}
+		buf.WriteString("\n")
+	}
+
+	for _, edge := range graph.Edges {
+		if len(edge.Label) > 0 {
+			fmt.Fprintf(buf, "  %s --> %s : %s\n", mermaidID(edge.From), mermaidID(edge.To), mermaidText(edge.Label))
+		} else {
+			fmt.Fprintf(buf, "  %s --> %s\n", mermaidID(edge.From), mermaidID(edge.To))
+		}
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,149 @@
+package protojson
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// graphNodes returns the nodes of a graph by ID
+func graphNodes(graph *TypeGraph) map[string]*GraphNode {
+	ret := make(map[string]*GraphNode)
+	for _, node := range graph.Nodes {
+		ret[node.ID] = node
+	}
+	return ret
+}
+
+// memberNames returns the names of the members of a node
+func memberNames(node *GraphNode) []string {
+	ret := make([]string, 0, len(node.Members))
+	for _, member := range node.Members {
+		ret = append(ret, member.Name)
+	}
+	return ret
+}
+
+func TestTypeGraphCollapseNested(t *testing.T) {
+	req := fixtureRequest(t, "")
+	// Return a type nested in a message which the service doesn't otherwise refer to
+	method := fixtureFile(t, req, "booking.proto").GetService()[0].GetMethod()[0]
+	method.OutputType = proto.String(".com.pseudomuto.protokit.v1.CreateListResponse.Status")
+	ctx := buildFixture(t, req)
+
+	tests := []struct {
+		name string
+		opts *graphOptions
+		// members are the members expected of message nodes, by ID
+		members map[string][]string
+		// absent are nodes which shouldn't be drawn
+		absent []string
+		edge   GraphEdge
+	}{
+		{
+			name: "everything",
+			opts: &graphOptions{CollapseNested: true},
+			members: map[string][]string{
+				"com.pseudomuto.protokit.v1.CreateListResponse": {"list", "status", "Status.code"},
+				"com.pseudomuto.protokit.v1.Item":               {"id", "title", "completed", "created_at", "details", "Status.PENDING", "Status.COMPLETED"},
+			},
+			absent: []string{"com.pseudomuto.protokit.v1.CreateListResponse.Status", "com.pseudomuto.protokit.v1.Item.Status"},
+			edge:   GraphEdge{From: "com.pseudomuto.protokit.v1.AddItemResponse", To: "com.pseudomuto.protokit.v1.Item", Label: "item"},
+		},
+		{
+			name: "service with a nested response",
+			opts: &graphOptions{Service: "com.pseudomuto.protokit.v1.BookingService", CollapseNested: true},
+			members: map[string][]string{
+				"com.pseudomuto.protokit.v1.CreateListResponse": {"list", "status", "Status.code"},
+			},
+			absent: []string{"com.pseudomuto.protokit.v1.CreateListResponse.Status", "com.pseudomuto.protokit.v1.Todo"},
+			edge:   GraphEdge{From: "com.pseudomuto.protokit.v1.BookingService.BookVehicle", To: "com.pseudomuto.protokit.v1.CreateListResponse", Label: "response"},
+		},
+		{
+			name: "not collapsed",
+			opts: &graphOptions{},
+			members: map[string][]string{
+				"com.pseudomuto.protokit.v1.CreateListResponse":        {"list", "status"},
+				"com.pseudomuto.protokit.v1.CreateListResponse.Status": {"code"},
+			},
+			edge: GraphEdge{From: "com.pseudomuto.protokit.v1.CreateListResponse", To: "com.pseudomuto.protokit.v1.CreateListResponse.Status", Label: "status"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph, err := buildTypeGraph(ctx, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodes := graphNodes(graph)
+
+			for id, want := range test.members {
+				node, found := nodes[id]
+				if !found {
+					t.Errorf("%s is not drawn", id)
+					continue
+				}
+				if got := memberNames(node); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s has members %q, want %q", id, got, want)
+				}
+			}
+			for _, id := range test.absent {
+				if _, found := nodes[id]; found {
+					t.Errorf("%s is drawn", id)
+				}
+			}
+
+			found := false
+			for _, edge := range graph.Edges {
+				found = found || *edge == test.edge
+			}
+			if !found {
+				t.Errorf("no edge %+v", test.edge)
+			}
+		})
+	}
+}
+
+func TestGraphParameterErrors(t *testing.T) {
+	tests := []string{
+		"format=dot,graph_service=com.pseudomuto.protokit.v1.Nope",
+		"format=mermaid,graph_service=com.pseudomuto.protokit.v1.Booking",
+		"format=mermaid,graph_package=nope",
+	}
+	for _, parameter := range tests {
+		resp, err := Generate(fixtureRequest(t, parameter), nil)
+		if err != nil {
+			t.Errorf("%s: failed instead of reporting an error: %v", parameter, err)
+		} else if resp.Error == nil {
+			t.Errorf("%s: no error reported", parameter)
+		}
+	}
+}
+
+func TestMermaidID(t *testing.T) {
+	tests := []struct {
+		fqn  string
+		want string
+	}{
+		{"my.pkg.Book", "my__pkg__Book"},
+		{"a.b_c", "a__b_uc"},
+		{"a_b.c", "a_ub__c"},
+		{"a._b", "a___ub"},
+		{"a_.b", "a_u__b"},
+		{"a-b", "a_x2db"},
+	}
+
+	seen := make(map[string]string)
+	for _, test := range tests {
+		got := mermaidID(test.fqn)
+		if got != test.want {
+			t.Errorf("mermaidID(%q) = %q, want %q", test.fqn, got, test.want)
+		}
+		if other, found := seen[got]; found {
+			t.Errorf("%q and %q have the same ID %q", other, test.fqn, got)
+		}
+		seen[got] = test.fqn
+	}
+}
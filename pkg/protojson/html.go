@@ -0,0 +1,329 @@
+package protojson
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// htmlAssets are the templates, stylesheet and scripts of the HTML output.
+// They're embedded in the binary so that the output doesn't depend on anything outside of it.
+//
+//go:embed html
+var htmlAssets embed.FS
+
+// htmlRenderer renders a context as a static HTML documentation site.
+//
+// Every service, message and enum gets its own page, and every package gets a page listing its contents.
+// Navigation and search are rendered client-side from `assets/data.js`, which is built at generation time,
+// so the site works offline (even when opened straight from disk).
+type htmlRenderer struct{}
+
+// htmlPackage is a package in the HTML output
+type htmlPackage struct {
+	Name     string
+	URL      string
+	Files    []*File
+	Services []*Service
+	Messages []*Message
+	Enums    []*Enum
+}
+
+// htmlPage is the data passed to the templates when rendering a page
+type htmlPage struct {
+	Kind     string
+	Title    string
+	Packages []*htmlPackage
+	Package  *htmlPackage
+	Service  *Service
+	Message  *Message
+	Enum     *Enum
+}
+
+// htmlNavEntry is an entry in the navigation tree or the search index
+type htmlNavEntry struct {
+	Name    string `json:"name"`
+	FQN     string `json:"fqn,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	URL     string `json:"url"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// htmlSiteData is the data in `assets/data.js`
+type htmlSiteData struct {
+	Packages []*struct {
+		htmlNavEntry
+		Entries []*htmlNavEntry `json:"entries"`
+	} `json:"packages"`
+	Search []*htmlNavEntry `json:"search"`
+}
+
+func (r *htmlRenderer) Render(ctx *Context) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	templates, err := template.New("").Funcs(htmlFuncs(ctx)).ParseFS(htmlAssets, "html/templates.html")
+	if err != nil {
+		return nil, err
+	}
+
+	packages := htmlPackages(ctx)
+	ret := make([]*plugin_go.CodeGeneratorResponse_File, 0)
+
+	render := func(filename string, page *htmlPage) error {
+		buf := new(bytes.Buffer)
+		if err := templates.ExecuteTemplate(buf, "layout", page); err != nil {
+			return err
+		}
+
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(filename),
+			Content: proto.String(buf.String()),
+		})
+		return nil
+	}
+
+	if err := render(htmlIndexPage, &htmlPage{Kind: "index", Title: "API Documentation", Packages: packages}); err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range packages {
+		title := pkg.Name
+		if len(title) == 0 {
+			title = "Files without a package"
+		}
+		if err := render(pkg.URL, &htmlPage{Kind: "package", Title: title, Package: pkg}); err != nil {
+			return nil, err
+		}
+		for _, service := range pkg.Services {
+			if err := render(htmlPageURL(service.FullName), &htmlPage{Kind: "service", Title: service.FullName, Service: service}); err != nil {
+				return nil, err
+			}
+		}
+		for _, message := range pkg.Messages {
+			if err := render(htmlPageURL(message.FullName), &htmlPage{Kind: "message", Title: message.FullName, Message: message}); err != nil {
+				return nil, err
+			}
+		}
+		for _, enum := range pkg.Enums {
+			if err := render(htmlPageURL(enum.FullName), &htmlPage{Kind: "enum", Title: enum.FullName, Enum: enum}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := json.Marshal(htmlData(ctx, packages))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, asset := range []string{"style.css", "site.js"} {
+		content, err := htmlAssets.ReadFile("html/" + asset)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String("assets/" + asset),
+			Content: proto.String(string(content)),
+		})
+	}
+	ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String("assets/data.js"),
+		Content: proto.String("window.SITE_DATA = " + string(data) + ";\n"),
+	})
+
+	return ret, nil
+}
+
+// htmlPackages groups the files, services, messages and enums in the context by package, in declaration order
+func htmlPackages(ctx *Context) []*htmlPackage {
+	ret := make([]*htmlPackage, 0)
+	byName := make(map[string]*htmlPackage)
+
+	for _, file := range ctx.OrderedFiles() {
+		pkg, found := byName[file.Package]
+		if !found {
+			pkg = &htmlPackage{Name: file.Package, URL: htmlPackageURL(file.Package)}
+			byName[file.Package] = pkg
+			ret = append(ret, pkg)
+		}
+
+		pkg.Files = append(pkg.Files, file)
+		for _, fqn := range file.Services {
+			pkg.Services = append(pkg.Services, ctx.Services[fqn])
+		}
+		for _, fqn := range file.Messages {
+			// Map entries are documented as part of the fields which use them
+			if message := ctx.Messages[fqn]; message != nil && !message.IsMapEntry {
+				pkg.Messages = append(pkg.Messages, message)
+			}
+		}
+		for _, fqn := range file.Enums {
+			pkg.Enums = append(pkg.Enums, ctx.Enums[fqn])
+		}
+	}
+
+	return ret
+}
+
+// htmlData builds the navigation tree and search index of the site
+func htmlData(ctx *Context, packages []*htmlPackage) *htmlSiteData {
+	data := &htmlSiteData{Search: make([]*htmlNavEntry, 0)}
+
+	addSearch := func(fqn string, name string, kind string, description string) {
+		summary, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+		data.Search = append(data.Search, &htmlNavEntry{
+			Name:    name,
+			FQN:     fqn,
+			Kind:    kind,
+			URL:     htmlTypeURL(ctx, fqn),
+			Summary: summary,
+		})
+	}
+
+	for _, pkg := range packages {
+		navPackage := &struct {
+			htmlNavEntry
+			Entries []*htmlNavEntry `json:"entries"`
+		}{htmlNavEntry: htmlNavEntry{Name: pkg.Name, URL: pkg.URL}, Entries: make([]*htmlNavEntry, 0)}
+		data.Packages = append(data.Packages, navPackage)
+
+		for _, service := range pkg.Services {
+			navPackage.Entries = append(navPackage.Entries, &htmlNavEntry{Name: ctx.localName(service.FullName), Kind: "service", URL: htmlPageURL(service.FullName)})
+			addSearch(service.FullName, service.Name, "service", service.Description)
+
+			for _, fqn := range service.Methods {
+				method := ctx.Methods[fqn]
+				addSearch(fqn, method.Name, "method", method.Description)
+			}
+		}
+		for _, message := range pkg.Messages {
+			navPackage.Entries = append(navPackage.Entries, &htmlNavEntry{Name: ctx.localName(message.FullName), Kind: "message", URL: htmlPageURL(message.FullName)})
+			addSearch(message.FullName, message.Name, "message", message.Description)
+
+			for _, fqn := range message.Fields {
+				field := ctx.Fields[fqn]
+				addSearch(fqn, field.Name, "field", field.Description)
+			}
+		}
+		for _, enum := range pkg.Enums {
+			navPackage.Entries = append(navPackage.Entries, &htmlNavEntry{Name: ctx.localName(enum.FullName), Kind: "enum", URL: htmlPageURL(enum.FullName)})
+			addSearch(enum.FullName, enum.Name, "enum", enum.Description)
+
+			for _, fqn := range enum.Values {
+				value := ctx.EnumValues[fqn]
+				addSearch(fqn, value.Name, "enum value", value.Description)
+			}
+		}
+	}
+
+	return data
+}
+
+// htmlIndexPage is the filename of the page which links to every package
+const htmlIndexPage = "index.html"
+
+// htmlPageURL returns the URL of the page of a service, message or enum. Types named like the index page (which
+// can only be top-level types without a package) are named differently; names with a hyphen can't be FQNs.
+func htmlPageURL(fqn string) string {
+	if fqn+".html" == htmlIndexPage {
+		return "type-" + fqn + ".html"
+	}
+	return fqn + ".html"
+}
+
+// htmlPackageURL returns the URL of the page of a package, which is named so it can't collide with the pages of types
+func htmlPackageURL(pkg string) string {
+	if len(pkg) == 0 {
+		return "no-package.html"
+	}
+	return "package-" + pkg + ".html"
+}
+
+// htmlTypeURL returns the URL documenting any object in the index.
+// Methods, fields and enum values are anchors within the page of their parent.
+// If `fqn` isn't in the index, or is external, an empty string is returned.
+func htmlTypeURL(ctx *Context, fqn string) string {
+	entry, found := ctx.Index[fqn]
+	if !found || entry.External {
+		return ""
+	}
+
+	switch entry.Type {
+	case "method":
+		return htmlPageURL(fqn[:strings.LastIndex(fqn, ".")]) + "#" + fqn
+	case "field", "enum_value":
+		return htmlPageURL(entry.Parent) + "#" + fqn
+	default:
+		return htmlPageURL(fqn)
+	}
+}
+
+// htmlFuncs are the functions available to the HTML templates
+func htmlFuncs(ctx *Context) template.FuncMap {
+	return template.FuncMap{
+		"isDeprecated": func(options map[string]interface{}) bool {
+			deprecated, _ := options["deprecated"].(bool)
+			return deprecated
+		},
+		"localName": ctx.localName,
+		"pageURL":   htmlPageURL,
+		"fileOf": func(fqn string) string {
+			return ctx.Index[fqn].File
+		},
+		"parentOf": func(fqn string) string {
+			return ctx.Index[fqn].Parent
+		},
+		"packageURL": func(fqn string) string {
+			return htmlPackageURL(ctx.Files[ctx.Index[fqn].File].Package)
+		},
+		"methodsOf": ctx.MethodsOf,
+		"fieldsOf":  ctx.FieldsOf,
+		"valuesOf":  ctx.ValuesOf,
+		"nestedTypes": func(message *Message) []string {
+			ret := make([]string, 0)
+			for _, fqn := range message.Messages {
+				if nested := ctx.Messages[fqn]; nested != nil && !nested.IsMapEntry {
+					ret = append(ret, fqn)
+				}
+			}
+			return append(ret, message.Enums...)
+		},
+		"fieldLabel": func(field *Field) string {
+			if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry {
+				return "map"
+			}
+			return strings.ToLower(strings.TrimPrefix(field.Label, "LABEL_"))
+		},
+		"typeLink": func(fqn string) template.HTML {
+			return htmlTypeLink(ctx, fqn)
+		},
+		"fieldType": func(field *Field) template.HTML {
+			if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+				key := ctx.Fields[entry.Fields[0]]
+				value := ctx.Fields[entry.Fields[1]]
+				return "map&lt;" + htmlTypeLink(ctx, key.FullType) + ", " + htmlTypeLink(ctx, value.FullType) + "&gt;"
+			}
+			return htmlTypeLink(ctx, field.FullType)
+		},
+	}
+}
+
+// htmlTypeLink renders a reference to the type `fqn`, linking to its page if it is generated, or to the
+// reference documentation if it is a well-known type
+func htmlTypeLink(ctx *Context, fqn string) template.HTML {
+	url := htmlTypeURL(ctx, fqn)
+	if len(url) == 0 {
+		if wellKnown, isWellKnown := wellKnownTypes[fqn]; isWellKnown {
+			return template.HTML(`<a href="` + template.HTMLEscapeString(wellKnown.URL) + `" title="` +
+				template.HTMLEscapeString(wellKnown.Description+" JSON: "+wellKnown.JSON) + `"><code>` +
+				template.HTMLEscapeString(fqn) + "</code></a>")
+		}
+		return template.HTML("<code>" + template.HTMLEscapeString(fqn) + "</code>")
+	}
+
+	return template.HTML(`<a href="` + template.HTMLEscapeString(url) + `"><code>` +
+		template.HTMLEscapeString(ctx.localName(fqn)) + "</code></a>")
+}
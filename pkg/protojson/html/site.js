@@ -0,0 +1,99 @@
+// Renders the navigation tree and the search box from the data in `data.js`.
+(function () {
+  var data = window.SITE_DATA;
+
+  function link(entry) {
+    var a = document.createElement("a");
+    a.href = entry.url;
+    a.textContent = entry.name;
+    return a;
+  }
+
+  function renderTree(container) {
+    var list = document.createElement("ul");
+
+    data.packages.forEach(function (pkg) {
+      var item = document.createElement("li");
+      var details = document.createElement("details");
+      var summary = document.createElement("summary");
+      summary.appendChild(link({ name: pkg.name || "(no package)", url: pkg.url }));
+      details.appendChild(summary);
+
+      var children = document.createElement("ul");
+      pkg.entries.forEach(function (entry) {
+        var child = document.createElement("li");
+        var kind = document.createElement("span");
+        kind.className = "kind";
+        kind.textContent = entry.kind;
+        child.appendChild(kind);
+        child.appendChild(link(entry));
+        children.appendChild(child);
+
+        if (window.location.pathname.endsWith("/" + entry.url)) {
+          details.open = true;
+        }
+      });
+      details.appendChild(children);
+
+      if (window.location.pathname.endsWith("/" + pkg.url)) {
+        details.open = true;
+      }
+
+      item.appendChild(details);
+      list.appendChild(item);
+    });
+
+    container.appendChild(list);
+  }
+
+  function search(query, results, tree) {
+    results.innerHTML = "";
+    query = query.trim().toLowerCase();
+
+    if (query.length === 0) {
+      tree.style.display = "";
+      return;
+    }
+    tree.style.display = "none";
+
+    var matches = data.search.filter(function (entry) {
+      return entry.fqn.toLowerCase().indexOf(query) >= 0 || entry.summary.toLowerCase().indexOf(query) >= 0;
+    });
+
+    // Prefer matches on the name itself
+    matches.sort(function (a, b) {
+      var aName = a.name.toLowerCase().indexOf(query) >= 0 ? 0 : 1;
+      var bName = b.name.toLowerCase().indexOf(query) >= 0 ? 0 : 1;
+      return aName - bName || a.fqn.localeCompare(b.fqn);
+    });
+
+    matches.slice(0, 100).forEach(function (entry) {
+      var item = document.createElement("li");
+      var kind = document.createElement("span");
+      kind.className = "kind";
+      kind.textContent = entry.kind;
+      item.appendChild(kind);
+      item.appendChild(link({ name: entry.fqn, url: entry.url }));
+
+      if (entry.summary.length > 0) {
+        var summary = document.createElement("span");
+        summary.className = "summary";
+        summary.textContent = entry.summary;
+        item.appendChild(summary);
+      }
+
+      results.appendChild(item);
+    });
+  }
+
+  document.addEventListener("DOMContentLoaded", function () {
+    var tree = document.getElementById("nav-tree");
+    var results = document.getElementById("search-results");
+    var input = document.getElementById("search");
+
+    renderTree(tree);
+    input.addEventListener("input", function () {
+      search(input.value, results, tree);
+    });
+  });
+})();
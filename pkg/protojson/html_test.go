@@ -0,0 +1,64 @@
+package protojson
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// htmlLink matches the relative links of an HTML page, without their anchors
+var htmlLink = regexp.MustCompile(`href="([^"#:]+)(#[^"]*)?"`)
+
+func TestHTMLPageNames(t *testing.T) {
+	req := fixtureRequest(t, "format=html")
+
+	// A file without a package, declaring a top-level type named like the index page
+	file := fixtureFile(t, req, "booking.proto")
+	file.Package = proto.String("")
+	for _, message := range file.GetMessageType() {
+		if message.GetName() == "Booking" {
+			message.Name = proto.String("index")
+		}
+	}
+	method := file.GetService()[0].GetMethod()[0]
+	method.InputType = proto.String(".index")
+	method.OutputType = proto.String(".BookingStatus")
+
+	pages := generateFixture(t, req)
+
+	tests := []struct {
+		page  string
+		title string
+	}{
+		{page: "index.html", title: "API Documentation"},
+		{page: "type-index.html", title: "index"},
+		{page: "no-package.html", title: "Files without a package"},
+		{page: "package-com.pseudomuto.protokit.v1.html", title: "com.pseudomuto.protokit.v1"},
+		{page: "BookingStatus.html", title: "BookingStatus"},
+		{page: "com.pseudomuto.protokit.v1.List.html", title: "com.pseudomuto.protokit.v1.List"},
+	}
+	for _, test := range tests {
+		content, found := pages[test.page]
+		if !found {
+			t.Errorf("%s isn't written", test.page)
+			continue
+		}
+		if !strings.Contains(content, "<title>"+test.title) {
+			t.Errorf("%s isn't the page of %s", test.page, test.title)
+		}
+	}
+
+	// Every page links only to pages which are written
+	for name, content := range pages {
+		if !strings.HasSuffix(name, ".html") {
+			continue
+		}
+		for _, link := range htmlLink.FindAllStringSubmatch(content, -1) {
+			if _, found := pages[link[1]]; !found {
+				t.Errorf("%s links to %s, which isn't written", name, link[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package protojson
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// httpRuleExtension is the field number of the `google.api.http` method option
+const httpRuleExtension = 72295728
+
+// Field numbers in `google.api.HttpRule`
+const (
+	httpRuleGet                = 2
+	httpRulePut                = 3
+	httpRulePost               = 4
+	httpRuleDelete             = 5
+	httpRulePatch              = 6
+	httpRuleBody               = 7
+	httpRuleCustom             = 8
+	httpRuleAdditionalBindings = 11
+	httpRuleResponseBody       = 12
+
+	// Field numbers in `google.api.CustomHttpPattern`
+	customHTTPPatternKind = 1
+	customHTTPPatternPath = 2
+)
+
+// HTTPRule is a REST binding of a method, decoded from its `google.api.http` option
+type HTTPRule struct {
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Body         string `json:"body,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+}
+
+// parseHTTPRules decodes the `google.api.http` option set on a method, if any.
+// The primary binding is returned first, followed by any `additional_bindings`.
+//
+// `google/api/annotations.proto` is usually imported rather than generated, so the option can't be decoded
+// through the usual custom option machinery; instead it is read directly from the unknown fields of `options`.
+func parseHTTPRules(options *descriptorpb.MethodOptions) []*HTTPRule {
+	raw := options.ProtoReflect().GetUnknown()
+
+	for len(raw) > 0 {
+		num, wireType, length := protowire.ConsumeTag(raw)
+		if length < 0 {
+			return nil
+		}
+		raw = raw[length:]
+
+		if num == httpRuleExtension && wireType == protowire.BytesType {
+			rule, length := protowire.ConsumeBytes(raw)
+			if length < 0 {
+				return nil
+			}
+			return decodeHTTPRule(rule)
+		}
+
+		length = protowire.ConsumeFieldValue(num, wireType, raw)
+		if length < 0 {
+			return nil
+		}
+		raw = raw[length:]
+	}
+
+	return nil
+}
+
+// decodeHTTPRule decodes an encoded `google.api.HttpRule`, flattening its additional bindings
+func decodeHTTPRule(raw []byte) []*HTTPRule {
+	rule := &HTTPRule{}
+	additional := make([]*HTTPRule, 0)
+
+	for len(raw) > 0 {
+		num, wireType, length := protowire.ConsumeTag(raw)
+		if length < 0 {
+			break
+		}
+		raw = raw[length:]
+
+		if wireType != protowire.BytesType {
+			length = protowire.ConsumeFieldValue(num, wireType, raw)
+			if length < 0 {
+				break
+			}
+			raw = raw[length:]
+			continue
+		}
+
+		value, length := protowire.ConsumeBytes(raw)
+		if length < 0 {
+			break
+		}
+		raw = raw[length:]
+
+		switch num {
+		case httpRuleGet:
+			rule.Method, rule.Path = "GET", string(value)
+		case httpRulePut:
+			rule.Method, rule.Path = "PUT", string(value)
+		case httpRulePost:
+			rule.Method, rule.Path = "POST", string(value)
+		case httpRuleDelete:
+			rule.Method, rule.Path = "DELETE", string(value)
+		case httpRulePatch:
+			rule.Method, rule.Path = "PATCH", string(value)
+		case httpRuleCustom:
+			rule.Method, rule.Path = decodeCustomHTTPPattern(value)
+		case httpRuleBody:
+			rule.Body = string(value)
+		case httpRuleResponseBody:
+			rule.ResponseBody = string(value)
+		case httpRuleAdditionalBindings:
+			additional = append(additional, decodeHTTPRule(value)...)
+		}
+	}
+
+	if len(rule.Method) == 0 {
+		return additional
+	}
+	return append([]*HTTPRule{rule}, additional...)
+}
+
+// decodeCustomHTTPPattern decodes an encoded `google.api.CustomHttpPattern` into its verb and path
+func decodeCustomHTTPPattern(raw []byte) (string, string) {
+	kind, path := "", ""
+
+	for len(raw) > 0 {
+		num, wireType, length := protowire.ConsumeTag(raw)
+		if length < 0 {
+			break
+		}
+		raw = raw[length:]
+
+		length = protowire.ConsumeFieldValue(num, wireType, raw)
+		if length < 0 {
+			break
+		}
+
+		if wireType == protowire.BytesType {
+			value, _ := protowire.ConsumeBytes(raw)
+			switch num {
+			case customHTTPPatternKind:
+				kind = strings.ToUpper(string(value))
+			case customHTTPPatternPath:
+				path = string(value)
+			}
+		}
+		raw = raw[length:]
+	}
+
+	return kind, path
+}
@@ -0,0 +1,316 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// JSON Schema output modes, selected with the `json_schema` parameter
+const (
+	// JSONSchemaBundle emits a single schema document containing every message under `$defs`
+	JSONSchemaBundle = "bundle"
+	// JSONSchemaMessages emits one schema document per message
+	JSONSchemaMessages = "messages"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaOf builds a schema object from alternating keys and values
+func schemaOf(pairs ...interface{}) *orderedMap {
+	ret := newOrderedMap()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		ret.Set(pairs[i].(string), pairs[i+1])
+	}
+	return ret
+}
+
+// primitiveSchema returns the schema for a scalar proto type (as in `Field.Type`), following the proto3 JSON mapping
+func primitiveSchema(protoType string) *orderedMap {
+	switch protoType {
+	case "double", "float":
+		// Non-finite values are encoded as strings
+		return schemaOf("oneOf", []interface{}{
+			schemaOf("type", "number"),
+			schemaOf("type", "string", "enum", []string{"NaN", "Infinity", "-Infinity"}),
+		})
+	case "int32", "sint32", "sfixed32":
+		return schemaOf("type", "integer", "minimum", int64(-1)<<31, "maximum", int64(1)<<31-1)
+	case "uint32", "fixed32":
+		return schemaOf("type", "integer", "minimum", 0, "maximum", int64(1)<<32-1)
+	case "int64", "sint64", "sfixed64":
+		// 64-bit integers are encoded as strings, since they can't be represented exactly by a JSON number
+		return schemaOf("type", "string", "pattern", "^-?[0-9]+$")
+	case "uint64", "fixed64":
+		return schemaOf("type", "string", "pattern", "^[0-9]+$")
+	case "bool":
+		return schemaOf("type", "boolean")
+	case "string":
+		return schemaOf("type", "string")
+	case "bytes":
+		return schemaOf("type", "string", "contentEncoding", "base64")
+	default:
+		return schemaOf()
+	}
+}
+
+// jsonSchemaBuilder builds JSON Schemas for the messages in a context
+type jsonSchemaBuilder struct {
+	ctx *Context
+	// ref returns the `$ref` pointing to the schema of the message `fqn`
+	ref func(fqn string) string
+}
+
+// generateJSONSchemas builds the JSON Schema output files for `ctx`, according to `params`
+func generateJSONSchemas(ctx *Context, params *Options) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	messages := make([]string, 0, len(ctx.Messages))
+	for _, fqn := range ctx.declaredOrder().Messages {
+		// Map entries are inlined into the fields which use them
+		if !ctx.Messages[fqn].IsMapEntry {
+			messages = append(messages, fqn)
+		}
+	}
+
+	ret := make([]*plugin_go.CodeGeneratorResponse_File, 0)
+
+	switch params.JSONSchema {
+	case JSONSchemaBundle:
+		builder := &jsonSchemaBuilder{ctx: ctx, ref: func(fqn string) string { return "#/$defs/" + fqn }}
+
+		defs := newOrderedMap()
+		for _, fqn := range messages {
+			defs.Set(fqn, builder.messageSchema(ctx.Messages[fqn]))
+		}
+
+		content, err := encodeJSONSchema(schemaOf("$schema", jsonSchemaDialect, "$defs", defs), params.Indent)
+		if err != nil {
+			return nil, err
+		}
+
+		filename := params.JSONSchemaOut
+		if filename == "" {
+			filename = "schema.json"
+		}
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(filename),
+			Content: proto.String(content),
+		})
+	case JSONSchemaMessages:
+		builder := &jsonSchemaBuilder{ctx: ctx, ref: jsonSchemaFilename}
+
+		dir := params.JSONSchemaOut
+		if dir == "" {
+			dir = "schemas"
+		}
+
+		for _, fqn := range messages {
+			schema := schemaOf("$schema", jsonSchemaDialect, "$id", jsonSchemaFilename(fqn))
+			message := builder.messageSchema(ctx.Messages[fqn])
+			for _, key := range message.Keys() {
+				value, _ := message.Get(key)
+				schema.Set(key, value)
+			}
+
+			content, err := encodeJSONSchema(schema, params.Indent)
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+				Name:    proto.String(path.Join(dir, jsonSchemaFilename(fqn))),
+				Content: proto.String(content),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown JSON Schema mode %q", params.JSONSchema)
+	}
+
+	return ret, nil
+}
+
+// jsonSchemaFilename is the name of the schema document of the message `fqn` when emitting one document per message
+func jsonSchemaFilename(fqn string) string {
+	return fqn + ".schema.json"
+}
+
+func encodeJSONSchema(schema *orderedMap, indent string) (string, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(schema); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// messageSchema builds the schema of a message
+func (b *jsonSchemaBuilder) messageSchema(message *Message) *orderedMap {
+	schema := schemaOf("title", message.Name)
+	if len(message.Description) > 0 {
+		schema.Set("description", message.Description)
+	}
+	if deprecated, _ := message.Options["deprecated"].(bool); deprecated {
+		schema.Set("deprecated", true)
+	}
+	schema.Set("type", "object")
+
+	properties := newOrderedMap()
+	required := make([]string, 0)
+	oneofs := make([]string, 0)
+	oneofMembers := make(map[string][]string)
+
+	for _, fqn := range message.Fields {
+		field := b.ctx.Fields[fqn]
+		name := jsonFieldName(field)
+
+		properties.Set(name, b.fieldSchema(field))
+
+		if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.String() {
+			required = append(required, name)
+		}
+
+		if len(field.Oneof) > 0 {
+			if _, found := oneofMembers[field.Oneof]; !found {
+				oneofs = append(oneofs, field.Oneof)
+			}
+			oneofMembers[field.Oneof] = append(oneofMembers[field.Oneof], name)
+		}
+	}
+
+	schema.Set("properties", properties)
+	if len(required) > 0 {
+		schema.Set("required", required)
+	}
+
+	// At most one member of each oneof may be set: exactly one of the alternatives below will match
+	constraints := make([]interface{}, 0)
+	for _, oneof := range oneofs {
+		members := oneofMembers[oneof]
+
+		alternatives := make([]interface{}, 0, len(members)+1)
+		present := make([]interface{}, 0, len(members))
+		for _, member := range members {
+			alternatives = append(alternatives, schemaOf("required", []string{member}))
+			present = append(present, schemaOf("required", []string{member}))
+		}
+		alternatives = append(alternatives, schemaOf("not", schemaOf("anyOf", present)))
+
+		constraints = append(constraints, schemaOf("oneOf", alternatives))
+	}
+
+	if len(constraints) == 1 {
+		value, _ := constraints[0].(*orderedMap).Get("oneOf")
+		schema.Set("oneOf", value)
+	} else if len(constraints) > 1 {
+		schema.Set("allOf", constraints)
+	}
+
+	return schema
+}
+
+// fieldSchema builds the schema of a field's value
+func (b *jsonSchemaBuilder) fieldSchema(field *Field) *orderedMap {
+	var schema *orderedMap
+
+	if entry, found := b.mapEntry(field); found {
+		// Maps are encoded as objects, with keys always encoded as strings
+		key := b.ctx.Fields[entry.Fields[0]]
+		value := b.ctx.Fields[entry.Fields[1]]
+
+		schema = schemaOf("type", "object")
+		switch key.Type {
+		case "string":
+		case "bool":
+			schema.Set("propertyNames", schemaOf("enum", []string{"true", "false"}))
+		case "uint32", "fixed32", "uint64", "fixed64":
+			schema.Set("propertyNames", schemaOf("pattern", "^[0-9]+$"))
+		default:
+			schema.Set("propertyNames", schemaOf("pattern", "^-?[0-9]+$"))
+		}
+		schema.Set("additionalProperties", b.typeSchema(value))
+	} else if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED.String() {
+		schema = schemaOf("type", "array", "items", b.typeSchema(field))
+	} else {
+		schema = b.typeSchema(field)
+	}
+
+	if len(field.Description) > 0 {
+		schema.Set("description", field.Description)
+	}
+	if deprecated, _ := field.Options["deprecated"].(bool); deprecated {
+		schema.Set("deprecated", true)
+	}
+
+	return schema
+}
+
+// typeSchema builds the schema of a single value of a field's type
+func (b *jsonSchemaBuilder) typeSchema(field *Field) *orderedMap {
+	if wellKnown, found := wellKnownTypes[field.FullType]; found {
+		return wellKnown.schema()
+	}
+
+	if _, found := b.ctx.Messages[field.FullType]; found {
+		return schemaOf("$ref", b.ref(field.FullType))
+	}
+
+	// Enums are written as the names of their values, but their numbers are accepted too
+	if enum, found := b.ctx.Enums[field.FullType]; found {
+		names := make([]string, 0, len(enum.Values))
+		numbers := make([]int32, 0, len(enum.Values))
+		for _, value := range enum.Values {
+			names = append(names, b.ctx.EnumValues[value].Name)
+			numbers = append(numbers, b.ctx.EnumValues[value].Value)
+		}
+		return schemaOf("anyOf", []interface{}{
+			schemaOf("type", "string", "enum", names),
+			schemaOf("type", "integer", "enum", numbers),
+		})
+	}
+
+	if field.Descriptor != nil {
+		switch field.Descriptor.GetType() {
+		case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+			// A message defined in a file which isn't being generated
+			return schemaOf("type", "object", "description", "External message "+field.FullType)
+		case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+			return schemaOf("type", "string", "description", "External enum "+field.FullType)
+		}
+	}
+
+	return primitiveSchema(field.Type)
+}
+
+// messageTypeSchema builds a schema for a reference to the message `fqn`, which may be a well-known type or a message
+// defined in a file which isn't being generated, neither of which have a schema of their own to refer to
+func (b *jsonSchemaBuilder) messageTypeSchema(fqn string) *orderedMap {
+	if wellKnown, found := wellKnownTypes[fqn]; found {
+		return wellKnown.schema()
+	}
+	if _, found := b.ctx.Messages[fqn]; found {
+		return schemaOf("$ref", b.ref(fqn))
+	}
+	return schemaOf("type", "object", "description", "External message "+fqn)
+}
+
+// mapEntry returns the map entry message which is the type of `field`, if `field` is a map
+func (b *jsonSchemaBuilder) mapEntry(field *Field) (*Message, bool) {
+	entry, found := b.ctx.Messages[field.FullType]
+	if !found || !entry.IsMapEntry || len(entry.Fields) != 2 {
+		return nil, false
+	}
+	return entry, true
+}
+
+// jsonFieldName returns the name of `field` in the proto3 JSON mapping
+func jsonFieldName(field *Field) string {
+	if len(field.JSONName) > 0 {
+		return field.JSONName
+	}
+	return field.Name
+}
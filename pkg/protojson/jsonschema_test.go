@@ -0,0 +1,169 @@
+package protojson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// schemaTestField builds a field of a test message, with a type name if its type is a message or enum
+func schemaTestField(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     fieldType.Enum(),
+	}
+	if len(typeName) > 0 {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+// schemaTestMapEntry builds the map entry message of a map field
+func schemaTestMapEntry(name string, key descriptorpb.FieldDescriptorProto_Type, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	value.Name = proto.String("value")
+	value.JsonName = proto.String("value")
+	value.Number = proto.Int32(2)
+	return &descriptorpb.DescriptorProto{
+		Name:    proto.String(name),
+		Field:   []*descriptorpb.FieldDescriptorProto{schemaTestField("key", 1, key, ""), value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+}
+
+// schemaTestMessage is a message with a field of every kind which the proto3 JSON mapping encodes differently
+func schemaTestMessage() *descriptorpb.DescriptorProto {
+	const kitchen = ".com.pseudomuto.protokit.v1.Kitchen"
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+
+	message := &descriptorpb.DescriptorProto{
+		Name: proto.String("Kitchen"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			schemaTestField("i64", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+			schemaTestField("u64", 2, descriptorpb.FieldDescriptorProto_TYPE_FIXED64, ""),
+			schemaTestField("i32", 3, descriptorpb.FieldDescriptorProto_TYPE_SINT32, ""),
+			schemaTestField("data", 4, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+			schemaTestField("kind", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".com.pseudomuto.protokit.v1.ListType"),
+			schemaTestField("created", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+			schemaTestField("ttl", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Duration"),
+			schemaTestField("attributes", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Struct"),
+			schemaTestField("count", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Int64Value"),
+			schemaTestField("tags", 10, stringType, ""),
+			schemaTestField("labels", 11, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, kitchen+".LabelsEntry"),
+			schemaTestField("flags", 12, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, kitchen+".FlagsEntry"),
+			schemaTestField("first", 13, stringType, ""),
+			schemaTestField("second", 14, stringType, ""),
+			schemaTestField("parent", 15, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, kitchen),
+		},
+		NestedType: []*descriptorpb.DescriptorProto{
+			schemaTestMapEntry("LabelsEntry", descriptorpb.FieldDescriptorProto_TYPE_UINT32, schemaTestField("", 0, stringType, "")),
+			schemaTestMapEntry("FlagsEntry", descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+				schemaTestField("", 0, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, kitchen)),
+		},
+		OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("choice")}},
+	}
+
+	for _, name := range []string{"tags", "labels", "flags"} {
+		for _, field := range message.Field {
+			if field.GetName() == name {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			}
+		}
+	}
+	message.Field[12].OneofIndex = proto.Int32(0)
+	message.Field[13].OneofIndex = proto.Int32(0)
+	return message
+}
+
+func TestJSONSchemaMapping(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "i64", want: `{"type": "string", "pattern": "^-?[0-9]+$"}`},
+		{field: "u64", want: `{"type": "string", "pattern": "^[0-9]+$"}`},
+		{field: "i32", want: `{"type": "integer", "minimum": -2147483648, "maximum": 2147483647}`},
+		{field: "data", want: `{"type": "string", "contentEncoding": "base64"}`},
+		{field: "kind", want: `{"anyOf": [{"type": "string", "enum": ["REMINDERS", "CHECKLIST"]}, {"type": "integer", "enum": [0, 1]}]}`},
+		{field: "created", want: `{"type": "string", "format": "date-time"}`},
+		{field: "ttl", want: `{"type": "string", "pattern": "^-?[0-9]+(\\.[0-9]{1,9})?s$"}`},
+		{field: "attributes", want: `{"type": "object"}`},
+		{field: "count", want: `{"anyOf": [{"type": "string", "pattern": "^-?[0-9]+$"}, {"type": "null"}]}`},
+		{field: "tags", want: `{"type": "array", "items": {"type": "string"}}`},
+		{field: "labels", want: `{"type": "object", "propertyNames": {"pattern": "^[0-9]+$"}, "additionalProperties": {"type": "string"}}`},
+		{field: "flags", want: `{"type": "object", "propertyNames": {"enum": ["true", "false"]}, "additionalProperties": {"$ref": "#/$defs/com.pseudomuto.protokit.v1.Kitchen"}}`},
+		{field: "parent", want: `{"$ref": "#/$defs/com.pseudomuto.protokit.v1.Kitchen"}`},
+	}
+
+	req := fixtureRequest(t, "json_schema=bundle")
+	addTestMessages(t, req, schemaTestMessage())
+	var bundle map[string]interface{}
+	if err := json.Unmarshal([]byte(generateFixture(t, req)["schema.json"]), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	kitchen := jsonPath(bundle, "$defs", "com.pseudomuto.protokit.v1.Kitchen")
+
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			var want interface{}
+			if err := json.Unmarshal([]byte(test.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := jsonPath(kitchen, "properties", test.field); !reflect.DeepEqual(got, want) {
+				encoded, _ := json.Marshal(got)
+				t.Errorf("got %s, want %s", encoded, test.want)
+			}
+		})
+	}
+
+	// Map entries are inlined, rather than defined
+	if jsonPath(bundle, "$defs", "com.pseudomuto.protokit.v1.Kitchen.LabelsEntry") != nil {
+		t.Errorf("map entries have definitions")
+	}
+
+	// Exactly one alternative matches: one member of the oneof, the other, or neither
+	var want interface{}
+	oneof := `[{"required": ["first"]}, {"required": ["second"]}, {"not": {"anyOf": [{"required": ["first"]}, {"required": ["second"]}]}}]`
+	if err := json.Unmarshal([]byte(oneof), &want); err != nil {
+		t.Fatal(err)
+	}
+	if got := jsonPath(kitchen, "oneOf"); !reflect.DeepEqual(got, want) {
+		encoded, _ := json.Marshal(got)
+		t.Errorf("got oneOf %s, want %s", encoded, oneof)
+	}
+}
+
+func TestJSONSchemaMessages(t *testing.T) {
+	req := fixtureRequest(t, "json_schema=messages,json_schema_out=out")
+	addTestMessages(t, req, schemaTestMessage())
+	files := generateFixture(t, req)
+
+	var kitchen map[string]interface{}
+	if err := json.Unmarshal([]byte(files["out/com.pseudomuto.protokit.v1.Kitchen.schema.json"]), &kitchen); err != nil {
+		t.Fatal(err)
+	}
+	if got := kitchen["$id"]; got != "com.pseudomuto.protokit.v1.Kitchen.schema.json" {
+		t.Errorf("got $id %v", got)
+	}
+
+	// Every document refers to the others by their filenames, relative to its own
+	for name, content := range files {
+		var document interface{}
+		if err := json.Unmarshal([]byte(content), &document); err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range findRefs(document) {
+			if _, found := files["out/"+ref]; !found {
+				t.Errorf("%s refers to %s, which isn't written", name, ref)
+			}
+		}
+	}
+	if got := jsonPath(kitchen, "properties", "parent", "$ref"); got != "com.pseudomuto.protokit.v1.Kitchen.schema.json" {
+		t.Errorf("got $ref %v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// LintAll enables every lint rule, when passed to the `lint` parameter
+const LintAll = "all"
+
+// lintRules are the built-in lint rules, by name
+var lintRules = map[string]func(l *linter){
+	"COMMENTS":                  lintComments,
+	"ENUM_ZERO_VALUE_SUFFIX":    lintEnumZeroValueSuffix,
+	"FIELD_LOWER_SNAKE_CASE":    lintFieldLowerSnakeCase,
+	"MESSAGE_PASCAL_CASE":       lintMessagePascalCase,
+	"RPC_REQUEST_RESPONSE_NAME": lintRPCRequestResponseName,
+	"UNUSED_MESSAGE":            lintUnusedMessage,
+}
+
+// validLintRule reports whether `name` can be passed to the `lint` parameter, or (unless it is LintAll) `lint_except`
+func validLintRule(name string) bool {
+	_, found := lintRules[name]
+	return found || name == LintAll
+}
+
+// LintFinding is a violation of a lint rule
+type LintFinding struct {
+	Rule     string          `json:"rule"`
+	FQN      string          `json:"fqn"`
+	Location *SourceLocation `json:"location"`
+	Message  string          `json:"message"`
+}
+
+// LintReport is the result of running the enabled lint rules over a context
+type LintReport struct {
+	Rules    []string       `json:"rules"`
+	Findings []*LintFinding `json:"findings"`
+}
+
+// linter runs lint rules over a context, accumulating their findings
+type linter struct {
+	ctx      *Context
+	order    *collectionOrder
+	locator  *sourceLocator
+	rule     string
+	findings []*LintFinding
+}
+
+// lintContext runs the lint rules `enabled`, except those in `except`. `enabled` may contain LintAll.
+func lintContext(ctx *Context, enabled []string, except []string) *LintReport {
+	rules := make([]string, 0)
+	for name := range lintRules {
+		if (containsString(enabled, LintAll) || containsString(enabled, name)) && !containsString(except, name) {
+			rules = append(rules, name)
+		}
+	}
+	sort.Strings(rules)
+
+	l := &linter{ctx: ctx, order: ctx.declaredOrder(), locator: newSourceLocator(ctx), findings: make([]*LintFinding, 0)}
+	for _, name := range rules {
+		l.rule = name
+		lintRules[name](l)
+	}
+
+	// Findings are reported in source order, like compiler errors
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i].Location, l.findings[j].Location
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return &LintReport{Rules: rules, Findings: l.findings}
+}
+
+// report records a finding of the current rule against the object `fqn`
+func (l *linter) report(fqn string, format string, args ...interface{}) {
+	l.findings = append(l.findings, &LintFinding{
+		Rule:     l.rule,
+		FQN:      fqn,
+		Location: l.locator.Locate(fqn),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// isMapEntryField reports whether the field `fqn` belongs to a synthetic map entry message
+func (l *linter) isMapEntryField(fqn string) bool {
+	entry, found := l.ctx.Index[fqn]
+	if !found {
+		return false
+	}
+	message, found := l.ctx.Messages[entry.Parent]
+	return found && message.IsMapEntry
+}
+
+// String formats the findings like compiler errors, one per line
+func (r *LintReport) String() string {
+	buf := new(strings.Builder)
+	for _, finding := range r.Findings {
+		fmt.Fprintf(buf, "%s: %s: %s\n", finding.Location, finding.Rule, finding.Message)
+	}
+	return buf.String()
+}
+
+// File encodes the report as a JSON output file
+func (r *LintReport) File(filename string, indent string) (*plugin_go.CodeGeneratorResponse_File, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(r); err != nil {
+		return nil, err
+	}
+
+	return &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String(filename),
+		Content: proto.String(buf.String()),
+	}, nil
+}
+
+// lintComments requires a comment on every service, method, message, field, enum and enum value
+func lintComments(l *linter) {
+	check := func(kind string, fqn string, description string) {
+		if !isDocumented(description) {
+			l.report(fqn, "%s %s has no comment", kind, fqn)
+		}
+	}
+
+	for _, fqn := range l.order.Services {
+		check("service", fqn, l.ctx.Services[fqn].Description)
+	}
+	for _, fqn := range l.order.Methods {
+		check("method", fqn, l.ctx.Methods[fqn].Description)
+	}
+	for _, fqn := range l.order.Messages {
+		if message := l.ctx.Messages[fqn]; !message.IsMapEntry {
+			check("message", fqn, message.Description)
+		}
+	}
+	for _, fqn := range l.order.Fields {
+		if !l.isMapEntryField(fqn) {
+			check("field", fqn, l.ctx.Fields[fqn].Description)
+		}
+	}
+	for _, fqn := range l.order.Enums {
+		check("enum", fqn, l.ctx.Enums[fqn].Description)
+	}
+	for _, fqn := range l.order.EnumValues {
+		check("enum value", fqn, l.ctx.EnumValues[fqn].Description)
+	}
+}
+
+// lintEnumZeroValueSuffix requires the zero value of every enum to end in `_UNSPECIFIED`
+func lintEnumZeroValueSuffix(l *linter) {
+	for _, fqn := range l.order.EnumValues {
+		value := l.ctx.EnumValues[fqn]
+		if value.Value == 0 && !strings.HasSuffix(value.Name, "_UNSPECIFIED") {
+			l.report(fqn, "enum zero value %s should end in _UNSPECIFIED", value.Name)
+		}
+	}
+}
+
+// lowerSnakeCase matches names such as `name` and `display_name2`
+var lowerSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// lintFieldLowerSnakeCase requires field names to be lower_snake_case
+func lintFieldLowerSnakeCase(l *linter) {
+	for _, fqn := range l.order.Fields {
+		if field := l.ctx.Fields[fqn]; !lowerSnakeCase.MatchString(field.Name) {
+			l.report(fqn, "field name %s should be lower_snake_case", field.Name)
+		}
+	}
+}
+
+// pascalCase matches names such as `Book` and `BookV2`
+var pascalCase = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// lintMessagePascalCase requires message names to be PascalCase
+func lintMessagePascalCase(l *linter) {
+	for _, fqn := range l.order.Messages {
+		if message := l.ctx.Messages[fqn]; !message.IsMapEntry && !pascalCase.MatchString(message.Name) {
+			l.report(fqn, "message name %s should be PascalCase", message.Name)
+		}
+	}
+}
+
+// lintRPCRequestResponseName requires the request and response of every method to be named after it,
+// as `<Method>Request` and `<Method>Response`, optionally prefixed with the service name.
+// Types which aren't generated (such as `google.protobuf.Empty`) can't be renamed, so aren't checked.
+func lintRPCRequestResponseName(l *linter) {
+	for _, fqn := range l.order.Methods {
+		method := l.ctx.Methods[fqn]
+		service := fqn[:strings.LastIndex(fqn, ".")]
+		serviceName := service[strings.LastIndex(service, ".")+1:]
+
+		check := func(kind string, typeName string, suffix string) {
+			if _, generated := l.ctx.Messages[typeName]; !generated {
+				return
+			}
+
+			name := l.ctx.localName(typeName)
+			if name != method.Name+suffix && name != serviceName+method.Name+suffix {
+				l.report(fqn, "%s of %s should be named %s%s or %s%s%s, not %s",
+					kind, method.Name, method.Name, suffix, serviceName, method.Name, suffix, name)
+			}
+		}
+
+		check("request", method.InputType, "Request")
+		check("response", method.OutputType, "Response")
+	}
+}
+
+// lintUnusedMessage reports messages which aren't used by any field or method
+func lintUnusedMessage(l *linter) {
+	used := make(map[string]bool)
+	for _, field := range l.ctx.Fields {
+		used[field.FullType] = true
+	}
+	for _, method := range l.ctx.Methods {
+		used[method.InputType] = true
+		used[method.OutputType] = true
+	}
+
+	for _, fqn := range l.order.Messages {
+		if message := l.ctx.Messages[fqn]; !message.IsMapEntry && !used[fqn] {
+			l.report(fqn, "message %s is not used by any field or method", fqn)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package protojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// lintFixture builds the fixtures, with messages added to todo.proto which break the naming rules.
+// The added messages have no comments or source locations.
+func lintFixture(t *testing.T) *Context {
+	t.Helper()
+
+	req := fixtureRequest(t, "")
+	tagged := testMessage("Tagged", [2]string{"labels", "Tagged.LabelsEntry"})
+	tagged.Field[0].Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	tagged.NestedType = append(tagged.NestedType,
+		schemaTestMapEntry("LabelsEntry", descriptorpb.FieldDescriptorProto_TYPE_STRING, schemaTestField("", 0, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")))
+	addTestMessages(t, req,
+		testMessage("bad_name", [2]string{"BadField", ""}, [2]string{"tagged", "Tagged"}),
+		tagged,
+	)
+	return buildFixture(t, req)
+}
+
+func TestLintRules(t *testing.T) {
+	ctx := lintFixture(t)
+
+	tests := []struct {
+		rule string
+		// want are the findings expected, as "location fqn"
+		want []string
+	}{
+		{
+			rule: "COMMENTS",
+			want: []string{
+				"todo.proto bad_name", "todo.proto Tagged", "todo.proto bad_name.BadField", "todo.proto bad_name.tagged",
+				"todo.proto Tagged.labels",
+			},
+		},
+		{
+			rule: "ENUM_ZERO_VALUE_SUFFIX",
+			want: []string{
+				"todo.proto:44:3 ListType.REMINDERS", "todo.proto:80:5 Item.Status.PENDING",
+				"todo_import.proto:13:3 ListItemDetailEnum.DEFAULT",
+			},
+		},
+		{
+			rule: "FIELD_LOWER_SNAKE_CASE",
+			want: []string{"todo.proto bad_name.BadField"},
+		},
+		{
+			rule: "MESSAGE_PASCAL_CASE",
+			want: []string{"todo.proto bad_name"},
+		},
+		{
+			rule: "RPC_REQUEST_RESPONSE_NAME",
+			want: []string{"booking.proto:21:3 BookingService.BookVehicle", "booking.proto:21:3 BookingService.BookVehicle"},
+		},
+		{
+			// Map entries are used by their fields, and Tagged by bad_name
+			rule: "UNUSED_MESSAGE",
+			want: []string{"todo.proto bad_name"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			report := lintContext(ctx, []string{test.rule}, nil)
+
+			got := make([]string, 0)
+			for _, finding := range report.Findings {
+				if finding.Rule != test.rule {
+					t.Errorf("got a finding of %s", finding.Rule)
+				}
+				got = append(got, finding.Location.String()+" "+strings.TrimPrefix(finding.FQN, fixturePackage))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got findings %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLintReport(t *testing.T) {
+	ctx := lintFixture(t)
+
+	// Findings of every rule are in source order, and formatted like compiler errors
+	report := lintContext(ctx, []string{LintAll}, []string{"COMMENTS", "FIELD_LOWER_SNAKE_CASE", "MESSAGE_PASCAL_CASE", "UNUSED_MESSAGE"})
+	if want := []string{"ENUM_ZERO_VALUE_SUFFIX", "RPC_REQUEST_RESPONSE_NAME"}; !reflect.DeepEqual(report.Rules, want) {
+		t.Errorf("got rules %q, want %q", report.Rules, want)
+	}
+
+	want := "booking.proto:21:3: RPC_REQUEST_RESPONSE_NAME: request of BookVehicle should be named BookVehicleRequest or BookingServiceBookVehicleRequest, not Booking\n" +
+		"booking.proto:21:3: RPC_REQUEST_RESPONSE_NAME: response of BookVehicle should be named BookVehicleResponse or BookingServiceBookVehicleResponse, not BookingStatus\n" +
+		"todo.proto:44:3: ENUM_ZERO_VALUE_SUFFIX: enum zero value REMINDERS should end in _UNSPECIFIED\n" +
+		"todo.proto:80:5: ENUM_ZERO_VALUE_SUFFIX: enum zero value PENDING should end in _UNSPECIFIED\n" +
+		"todo_import.proto:13:3: ENUM_ZERO_VALUE_SUFFIX: enum zero value DEFAULT should end in _UNSPECIFIED\n"
+	if got := report.String(); got != want {
+		t.Errorf("got report\n%s\nwant\n%s", got, want)
+	}
+
+	// Only the listed rules run, less the exceptions
+	report = lintContext(ctx, []string{"MESSAGE_PASCAL_CASE", "UNUSED_MESSAGE"}, []string{"UNUSED_MESSAGE"})
+	if want := []string{"MESSAGE_PASCAL_CASE"}; !reflect.DeepEqual(report.Rules, want) {
+		t.Errorf("got rules %q, want %q", report.Rules, want)
+	}
+	if len(report.Findings) != 1 {
+		t.Errorf("got findings %v", report.Findings)
+	}
+}
+
+func TestLintParameters(t *testing.T) {
+	// Findings are warnings, unless `lint_fail` is set
+	req := fixtureRequest(t, "lint=ENUM_ZERO_VALUE_SUFFIX,lint_out=lint.json")
+	warnings := make([]string, 0)
+	resp, err := Generate(req, func(message string) { warnings = append(warnings, message) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "todo.proto:44:3: ENUM_ZERO_VALUE_SUFFIX") {
+		t.Errorf("got error %q and warnings %q", resp.GetError(), warnings)
+	}
+	found := false
+	for _, file := range resp.GetFile() {
+		found = found || file.GetName() == "lint.json"
+	}
+	if !found {
+		t.Errorf("lint.json isn't written")
+	}
+
+	resp, err = Generate(fixtureRequest(t, "lint=ENUM_ZERO_VALUE_SUFFIX,lint_fail=true"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.GetError(), "ENUM_ZERO_VALUE_SUFFIX") {
+		t.Errorf("got error %q", resp.GetError())
+	}
+}
@@ -0,0 +1,354 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Reader reads the objects of a `json` output of the plugin on demand, for outputs too large to decode at once.
+// Opening the output only splits it into the raw JSON of each object; each object is decoded the first time it is
+// looked up, and then kept. Reader has the same navigation helpers as Context.
+//
+// If an object can't be decoded, it is treated as missing, and the error is returned by `Err`.
+type Reader struct {
+	Meta  *Meta
+	Index map[string]*IndexEntry
+
+	// raw is the undecoded JSON of each object, by collection and FQN (or name, for files)
+	raw map[string]map[string]json.RawMessage
+	// ctx holds the objects which have been decoded
+	ctx *Context
+	err error
+}
+
+// readerCollections are the collections of an output, with the key which identifies their objects in the array layout
+var readerCollections = []struct {
+	name string
+	key  string
+}{
+	{"files", "name"},
+	{"services", "full_name"},
+	{"methods", "full_name"},
+	{"messages", "full_name"},
+	{"fields", "full_name"},
+	{"enums", "full_name"},
+	{"enum_values", "full_name"},
+}
+
+// NewReader reads the `json` output of the plugin, in any collection layout, without decoding its objects
+func NewReader(r io.Reader) (*Reader, error) {
+	raw := make(map[string]json.RawMessage)
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("not a JSON output of protoc-gen-json: %w", err)
+	}
+
+	ret := &Reader{raw: make(map[string]map[string]json.RawMessage), ctx: NewContext()}
+
+	if meta, found := raw["meta"]; found {
+		if err := json.Unmarshal(meta, &ret.Meta); err != nil {
+			return nil, fmt.Errorf("failed to read meta: %w", err)
+		}
+	}
+	if index, found := raw["index"]; found {
+		if err := json.Unmarshal(index, &ret.ctx.Index); err != nil {
+			return nil, fmt.Errorf("failed to read index: %w", err)
+		}
+	}
+	ret.ctx.Meta = ret.Meta
+	ret.Index = ret.ctx.Index
+
+	for _, collection := range readerCollections {
+		objects, err := splitCollection(raw[collection.name], collection.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", collection.name, err)
+		}
+		ret.raw[collection.name] = objects
+	}
+
+	return ret, nil
+}
+
+// OpenReader reads a `json` output file without decoding its objects (see `NewReader`)
+func OpenReader(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// splitCollection splits a collection into the raw JSON of its objects. Collections are objects keyed by FQN in the
+// sorted and declared layouts, and arrays in the array layout, in which case each object is keyed by its `key`.
+func splitCollection(raw json.RawMessage, key string) (map[string]json.RawMessage, error) {
+	ret := make(map[string]json.RawMessage)
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ret, nil
+	}
+
+	if raw[0] != '[' {
+		err := json.Unmarshal(raw, &ret)
+		return ret, err
+	}
+
+	// Items are read one at a time, and only as far as their key, so large arrays aren't parsed twice
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return nil, err
+		}
+		name, err := objectKey(item, key)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = item
+	}
+	return ret, nil
+}
+
+// objectKey returns the string value of the key `key` of a JSON object, reading no further than that key
+func objectKey(object json.RawMessage, key string) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return "", fmt.Errorf("object without a %s: not an object", key)
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if token != key {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		var name string
+		if err := dec.Decode(&name); err != nil {
+			return "", fmt.Errorf("object without a %s: %w", key, err)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("object without a %s", key)
+}
+
+// decodeObject decodes the object `fqn` of a collection into `into`, unless it already has been
+func decodeObject[T any](r *Reader, collection string, fqn string, into map[string]*T) (*T, bool) {
+	if object, found := into[fqn]; found {
+		return object, true
+	}
+
+	raw, found := r.raw[collection][fqn]
+	if !found {
+		return nil, false
+	}
+
+	object := new(T)
+	if err := json.Unmarshal(raw, object); err != nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("failed to read %s %s: %w", collection, fqn, err)
+		}
+		return nil, false
+	}
+	into[fqn] = object
+	return object, true
+}
+
+// Err returns the first error decoding an object, if any
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// File returns the file `name`
+func (r *Reader) File(name string) (*File, bool) {
+	return decodeObject(r, "files", name, r.ctx.Files)
+}
+
+// Service returns the service `fqn`
+func (r *Reader) Service(fqn string) (*Service, bool) {
+	return decodeObject(r, "services", fqn, r.ctx.Services)
+}
+
+// Method returns the method `fqn`
+func (r *Reader) Method(fqn string) (*Method, bool) {
+	return decodeObject(r, "methods", fqn, r.ctx.Methods)
+}
+
+// Message returns the message `fqn`
+func (r *Reader) Message(fqn string) (*Message, bool) {
+	return decodeObject(r, "messages", fqn, r.ctx.Messages)
+}
+
+// Field returns the field `fqn`
+func (r *Reader) Field(fqn string) (*Field, bool) {
+	return decodeObject(r, "fields", fqn, r.ctx.Fields)
+}
+
+// Enum returns the enum `fqn`
+func (r *Reader) Enum(fqn string) (*Enum, bool) {
+	return decodeObject(r, "enums", fqn, r.ctx.Enums)
+}
+
+// EnumValue returns the enum value `fqn`
+func (r *Reader) EnumValue(fqn string) (*EnumValue, bool) {
+	return decodeObject(r, "enum_values", fqn, r.ctx.EnumValues)
+}
+
+// Lookup returns the object with the FQN `fqn`, using the index to find its collection
+func (r *Reader) Lookup(fqn string) (interface{}, bool) {
+	entry, found := r.Index[fqn]
+	if !found {
+		return nil, false
+	}
+	return r.object(entry.Collection, fqn)
+}
+
+// object decodes the object `fqn` of a collection
+func (r *Reader) object(collection string, fqn string) (interface{}, bool) {
+	var ret interface{}
+	found := false
+
+	switch collection {
+	case "files":
+		ret, found = r.File(fqn)
+	case "services":
+		ret, found = r.Service(fqn)
+	case "methods":
+		ret, found = r.Method(fqn)
+	case "messages":
+		ret, found = r.Message(fqn)
+	case "fields":
+		ret, found = r.Field(fqn)
+	case "enums":
+		ret, found = r.Enum(fqn)
+	case "enum_values":
+		ret, found = r.EnumValue(fqn)
+	}
+
+	if !found {
+		return nil, false
+	}
+	return ret, true
+}
+
+func (r *Reader) indexEntry(fqn string) (*IndexEntry, bool) {
+	entry, found := r.Index[fqn]
+	return entry, found
+}
+
+func (r *Reader) allMethods() []*Method {
+	ret := make([]*Method, 0, len(r.raw["methods"]))
+	for fqn := range r.raw["methods"] {
+		if method, found := r.Method(fqn); found {
+			ret = append(ret, method)
+		}
+	}
+	return ret
+}
+
+// MethodsOf returns the methods of a service, in declaration order
+func (r *Reader) MethodsOf(service *Service) []*Method {
+	ret := make([]*Method, 0, len(service.Methods))
+	for _, fqn := range service.Methods {
+		if method, found := r.Method(fqn); found {
+			ret = append(ret, method)
+		}
+	}
+	return ret
+}
+
+// FieldsOf returns the fields of a message, in declaration order
+func (r *Reader) FieldsOf(message *Message) []*Field {
+	ret := make([]*Field, 0, len(message.Fields))
+	for _, fqn := range message.Fields {
+		if field, found := r.Field(fqn); found {
+			ret = append(ret, field)
+		}
+	}
+	return ret
+}
+
+// ValuesOf returns the values of an enum, in declaration order
+func (r *Reader) ValuesOf(enum *Enum) []*EnumValue {
+	ret := make([]*EnumValue, 0, len(enum.Values))
+	for _, fqn := range enum.Values {
+		if value, found := r.EnumValue(fqn); found {
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
+
+// Parent returns the object which declares `v` (see `Context.Parent`)
+func (r *Reader) Parent(v interface{}) (interface{}, bool) {
+	return parentOf(r, v)
+}
+
+// Resolve returns the message or enum a type reference refers to (see `Context.Resolve`)
+func (r *Reader) Resolve(typeName string) (interface{}, bool) {
+	return resolveType(r, typeName)
+}
+
+// MethodsUsing returns the methods which take or return `message`, ordered by FQN.
+// Every method is decoded to find them.
+func (r *Reader) MethodsUsing(message *Message) []*Method {
+	return methodsUsing(r, message)
+}
+
+// Context decodes every object which hasn't been yet, and returns them all as a context
+func (r *Reader) Context() (*Context, error) {
+	for _, collection := range readerCollections {
+		for key := range r.raw[collection.name] {
+			r.object(collection.name, key)
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.ctx, nil
+}
+
+// ReadContext reads a context back from the `json` output of the plugin, in any collection layout.
+//
+// Only what is in the output is restored: descriptors and custom option definitions are not, and keys which were
+// added in later versions of the output format than the one read are left empty.
+func ReadContext(r io.Reader) (*Context, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return reader.Context()
+}
+
+// ReadContextFile reads a context back from a `json` output file (see `ReadContext`)
+func ReadContextFile(path string) (*Context, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ctx, err := ReadContext(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,206 @@
+package protojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixturePackage = "com.pseudomuto.protokit.v1."
+
+// fullNames returns the FQNs of objects, in order
+func fullNames(objects ...interface{}) []string {
+	ret := make([]string, 0, len(objects))
+	for _, object := range objects {
+		ret = append(ret, strings.TrimPrefix(fullNameOf(object), fixturePackage))
+	}
+	return ret
+}
+
+func TestReader(t *testing.T) {
+	contexts := make(map[string]*Context)
+
+	for _, layout := range []string{"sorted", "declared", "array"} {
+		t.Run(layout, func(t *testing.T) {
+			output := generateFixture(t, fixtureRequest(t, "collections="+layout))["output.json"]
+			r, err := NewReader(strings.NewReader(output))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if file, found := r.File("todo.proto"); !found || file.Package != strings.TrimSuffix(fixturePackage, ".") {
+				t.Errorf("got file %+v", file)
+			}
+			service, found := r.Service(fixturePackage + "Todo")
+			if !found {
+				t.Fatal("Todo isn't found")
+			}
+			if got, want := fullNames(toInterfaces(r.MethodsOf(service))...), []string{"Todo.CreateList", "Todo.AddItem"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got methods %q, want %q", got, want)
+			}
+			list, found := r.Message(fixturePackage + "List")
+			if !found {
+				t.Fatal("List isn't found")
+			}
+			if got, want := fullNames(toInterfaces(r.FieldsOf(list))...), []string{"List.id", "List.name", "List.type", "List.created_at", "List.details"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got fields %q, want %q", got, want)
+			}
+			status, found := r.Enum(fixturePackage + "Item.Status")
+			if !found {
+				t.Fatal("Item.Status isn't found")
+			}
+			if got, want := fullNames(toInterfaces(r.ValuesOf(status))...), []string{"Item.Status.PENDING", "Item.Status.COMPLETED"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got values %q, want %q", got, want)
+			}
+
+			// Lookup finds objects of every collection through the index, and returns the same objects as the getters
+			method, _ := r.Method(fixturePackage + "Todo.AddItem")
+			field, _ := r.Field(fixturePackage + "List.type")
+			value, _ := r.EnumValue(fixturePackage + "Item.Status.PENDING")
+			for _, want := range []interface{}{service, method, list, field, status, value} {
+				if got, found := r.Lookup(fullNameOf(want)); !found || got != want {
+					t.Errorf("Lookup(%s) got %v", fullNameOf(want), got)
+				}
+			}
+			if _, found := r.Lookup(fixturePackage + "Missing"); found {
+				t.Errorf("Lookup found a missing object")
+			}
+
+			tests := []struct {
+				name   string
+				object interface{}
+				want   []string
+			}{
+				{name: "method", object: method, want: []string{"Todo"}},
+				{name: "field", object: field, want: []string{"List"}},
+				{name: "enum value", object: value, want: []string{"Item.Status"}},
+				{name: "nested enum", object: status, want: []string{"Item"}},
+				{name: "top-level message", object: list, want: []string{}},
+				{name: "service", object: service, want: []string{}},
+			}
+			for _, test := range tests {
+				parents := make([]interface{}, 0)
+				if parent, found := r.Parent(test.object); found {
+					parents = append(parents, parent)
+				}
+				if got := fullNames(parents...); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("%s: got parent %q, want %q", test.name, got, test.want)
+				}
+			}
+
+			if resolved, found := r.Resolve(field.FullType); !found || fullNameOf(resolved) != fixturePackage+"ListType" {
+				t.Errorf("got %v resolving %s", resolved, field.FullType)
+			}
+			if resolved, found := r.Resolve(method.InputType); !found || resolved.(*Message).FullName != method.InputType {
+				t.Errorf("got %v resolving %s", resolved, method.InputType)
+			}
+			for _, typeName := range []string{"string", fixturePackage + "List.name", ".google.protobuf.Timestamp"} {
+				if resolved, found := r.Resolve(typeName); found {
+					t.Errorf("got %v resolving %s", resolved, typeName)
+				}
+			}
+
+			request, _ := r.Message(method.InputType)
+			if got, want := fullNames(toInterfaces(r.MethodsUsing(request))...), []string{"Todo.AddItem"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("got methods using %s %q, want %q", request.FullName, got, want)
+			}
+
+			// Decoding the rest matches reading the whole context at once
+			got, err := r.Context()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ReadContext(strings.NewReader(output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoding objects on demand didn't read the same context as ReadContext")
+			}
+			if r.Err() != nil {
+				t.Errorf("got error %v", r.Err())
+			}
+
+			// The layouts only differ in how the collections are written
+			got.Meta = nil
+			contexts[layout] = got
+		})
+	}
+
+	if !reflect.DeepEqual(contexts["sorted"], contexts["array"]) || !reflect.DeepEqual(contexts["sorted"], contexts["declared"]) {
+		t.Errorf("the layouts read different contexts")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	r, err := NewReader(strings.NewReader(`{"messages": [{"full_name": "a.B", "fields": "a.B.c"}, {"full_name": "a.C"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An object which can't be decoded is missing, and its error is kept
+	if _, found := r.Message("a.B"); found {
+		t.Errorf("found a message which can't be decoded")
+	}
+	if _, found := r.Message("a.C"); !found {
+		t.Errorf("a.C isn't found")
+	}
+	if r.Err() == nil || !strings.Contains(r.Err().Error(), "messages a.B") {
+		t.Errorf("got error %v", r.Err())
+	}
+	if _, err := r.Context(); err == nil {
+		t.Errorf("read a context with an object which can't be decoded")
+	}
+
+	for _, output := range []string{`[]`, `{"messages": [{"name": "B"}]}`, `{"messages": [{"full_name": 1}]}`, `{"messages": [1]}`, `{"index": []}`} {
+		if _, err := NewReader(strings.NewReader(output)); err == nil {
+			t.Errorf("read %s", output)
+		}
+	}
+}
+
+func TestSplitCollection(t *testing.T) {
+	// Keys of nested objects aren't mistaken for the key of an item
+	got, err := splitCollection(json.RawMessage(`[{"options": {"full_name": "a.X"}, "full_name": "a.B"}, {"full_name": "a.C", "options": {}}]`), "full_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]json.RawMessage{
+		"a.B": json.RawMessage(`{"options": {"full_name": "a.X"}, "full_name": "a.B"}`),
+		"a.C": json.RawMessage(`{"full_name": "a.C", "options": {}}`),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// BenchmarkNewReader opens an output with a thousand more messages than the fixtures, in each collection layout
+func BenchmarkNewReader(b *testing.B) {
+	for _, layout := range []string{"sorted", "array"} {
+		req := fixtureRequest(b, "collections="+layout)
+		for i := 0; i < 1000; i++ {
+			addTestMessages(b, req, testMessage(fmt.Sprintf("Bench%d", i), [2]string{"id", ""}, [2]string{"name", ""}, [2]string{"list", "List"}))
+		}
+		output := generateFixture(b, req)["output.json"]
+
+		b.Run(layout, func(b *testing.B) {
+			b.SetBytes(int64(len(output)))
+			for i := 0; i < b.N; i++ {
+				if _, err := NewReader(strings.NewReader(output)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// toInterfaces converts a slice of objects to a slice of interfaces
+func toInterfaces[T any](objects []T) []interface{} {
+	ret := make([]interface{}, 0, len(objects))
+	for _, object := range objects {
+		ret = append(ret, object)
+	}
+	return ret
+}
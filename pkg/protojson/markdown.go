@@ -0,0 +1,331 @@
+package protojson
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// Page groupings of the Markdown output, selected with the `markdown_pages` parameter
+const (
+	// MarkdownPagesFile renders one page per proto file
+	MarkdownPagesFile = "file"
+	// MarkdownPagesPackage renders one page per proto package
+	MarkdownPagesPackage = "package"
+)
+
+// markdownRenderer renders a context as Markdown documentation, with one page per file or package
+// and an `index.md` linking to every page
+type markdownRenderer struct {
+	pages string
+}
+
+func newMarkdownRenderer(params *Options) (*markdownRenderer, error) {
+	switch params.MarkdownPages {
+	case MarkdownPagesFile, MarkdownPagesPackage:
+		return &markdownRenderer{pages: params.MarkdownPages}, nil
+	default:
+		return nil, fmt.Errorf("unknown Markdown page grouping %q", params.MarkdownPages)
+	}
+}
+
+// markdownPage is a single page of the Markdown output
+type markdownPage struct {
+	Title    string
+	Filename string
+	Files    []*File
+}
+
+// markdownIndexPage is the filename of the page which links to every other page
+const markdownIndexPage = "index.md"
+
+// newMarkdownPage creates the page which documents `file` in the page grouping `pages`, without any files yet.
+// Pages which would be named like the index page are named differently; names with a hyphen can't be package names.
+func newMarkdownPage(pages string, file *File) *markdownPage {
+	if pages == MarkdownPagesPackage {
+		switch {
+		case len(file.Package) == 0:
+			return &markdownPage{Title: "Files without a package", Filename: "no-package.md"}
+		case file.Package+".md" == markdownIndexPage:
+			return &markdownPage{Title: "Package " + file.Package, Filename: "package-" + file.Package + ".md"}
+		default:
+			return &markdownPage{Title: "Package " + file.Package, Filename: file.Package + ".md"}
+		}
+	}
+
+	filename := strings.TrimSuffix(file.Name, ".proto") + ".md"
+	if filename == markdownIndexPage {
+		filename = file.Name + ".md"
+	}
+	return &markdownPage{Title: file.Name, Filename: filename}
+}
+
+// markdownPageRenderer renders a single page, and resolves links from it to other pages
+type markdownPageRenderer struct {
+	ctx   *Context
+	page  *markdownPage
+	pages map[string]*markdownPage
+	// pageOf returns the key in `pages` of the page which documents the file `name`
+	pageOf func(name string) string
+	buf    *strings.Builder
+}
+
+func (r *markdownRenderer) Render(ctx *Context) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	pageOf := func(name string) string { return name }
+	if r.pages == MarkdownPagesPackage {
+		pageOf = func(name string) string { return ctx.Files[name].Package }
+	}
+
+	// Group the files into pages
+	keys := make([]string, 0)
+	pages := make(map[string]*markdownPage)
+	for _, file := range ctx.OrderedFiles() {
+		key := pageOf(file.Name)
+
+		page, found := pages[key]
+		if !found {
+			page = newMarkdownPage(r.pages, file)
+
+			keys = append(keys, key)
+			pages[key] = page
+		}
+
+		page.Files = append(page.Files, file)
+	}
+
+	ret := make([]*plugin_go.CodeGeneratorResponse_File, 0, len(pages)+1)
+
+	index := new(strings.Builder)
+	index.WriteString("# API Documentation\n\n")
+	for _, key := range keys {
+		page := pages[key]
+		fmt.Fprintf(index, "- [%s](%s)\n", markdownEscape(page.Title), page.Filename)
+
+		renderer := &markdownPageRenderer{ctx: ctx, page: page, pages: pages, pageOf: pageOf, buf: new(strings.Builder)}
+		renderer.render()
+
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(page.Filename),
+			Content: proto.String(renderer.buf.String()),
+		})
+	}
+
+	ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String(markdownIndexPage),
+		Content: proto.String(index.String()),
+	})
+
+	return ret, nil
+}
+
+// render renders the page into `r.buf`
+func (r *markdownPageRenderer) render() {
+	fmt.Fprintf(r.buf, "# %s\n\n", markdownEscape(r.page.Title))
+
+	for _, file := range r.page.Files {
+		if len(file.Description) > 0 {
+			fmt.Fprintf(r.buf, "%s\n\n", file.Description)
+		}
+	}
+
+	services := make([]string, 0)
+	messages := make([]string, 0)
+	enums := make([]string, 0)
+	for _, file := range r.page.Files {
+		services = append(services, file.Services...)
+		enums = append(enums, file.Enums...)
+
+		for _, fqn := range file.Messages {
+			// Map entries are documented as part of the fields which use them
+			if message, found := r.ctx.Messages[fqn]; found && !message.IsMapEntry {
+				messages = append(messages, fqn)
+			}
+		}
+	}
+
+	if len(services) > 0 {
+		r.buf.WriteString("## Services\n\n")
+		for _, fqn := range services {
+			r.renderService(r.ctx.Services[fqn])
+		}
+	}
+
+	if len(messages) > 0 {
+		r.buf.WriteString("## Messages\n\n")
+		for _, fqn := range messages {
+			r.renderMessage(r.ctx.Messages[fqn])
+		}
+	}
+
+	if len(enums) > 0 {
+		r.buf.WriteString("## Enums\n\n")
+		for _, fqn := range enums {
+			r.renderEnum(r.ctx.Enums[fqn])
+		}
+	}
+}
+
+func (r *markdownPageRenderer) renderService(service *Service) {
+	r.renderHeading(service.FullName, service.Description, service.Options)
+
+	r.buf.WriteString("| Method | Request | Response | Description |\n")
+	r.buf.WriteString("|--------|---------|----------|-------------|\n")
+	for _, fqn := range service.Methods {
+		method := r.ctx.Methods[fqn]
+		fmt.Fprintf(r.buf, "| %s%s | %s | %s | %s |\n",
+			anchorTag(method.FullName),
+			markdownEscape(method.Name)+deprecatedBadge(method.Options),
+			r.typeLink(method.InputType),
+			r.typeLink(method.OutputType),
+			markdownTableCell(method.Description),
+		)
+	}
+	r.buf.WriteString("\n")
+}
+
+func (r *markdownPageRenderer) renderMessage(message *Message) {
+	r.renderHeading(message.FullName, message.Description, message.Options)
+
+	if len(message.Fields) == 0 {
+		r.buf.WriteString("This message has no fields.\n\n")
+		return
+	}
+
+	r.buf.WriteString("| Field | Number | Type | Label | Description |\n")
+	r.buf.WriteString("|-------|--------|------|-------|-------------|\n")
+	for _, fqn := range message.Fields {
+		field := r.ctx.Fields[fqn]
+		fmt.Fprintf(r.buf, "| %s%s | %d | %s | %s | %s |\n",
+			anchorTag(field.FullName),
+			markdownEscape(field.Name)+deprecatedBadge(field.Options),
+			field.Number,
+			r.fieldTypeLink(field),
+			r.fieldLabel(field),
+			markdownTableCell(field.Description),
+		)
+	}
+	r.buf.WriteString("\n")
+}
+
+func (r *markdownPageRenderer) renderEnum(enum *Enum) {
+	r.renderHeading(enum.FullName, enum.Description, enum.Options)
+
+	r.buf.WriteString("| Name | Number | Description |\n")
+	r.buf.WriteString("|------|--------|-------------|\n")
+	for _, fqn := range enum.Values {
+		value := r.ctx.EnumValues[fqn]
+		fmt.Fprintf(r.buf, "| %s%s | %d | %s |\n",
+			anchorTag(value.FullName),
+			markdownEscape(value.Name)+deprecatedBadge(value.Options),
+			value.Value,
+			markdownTableCell(value.Description),
+		)
+	}
+	r.buf.WriteString("\n")
+}
+
+// renderHeading renders the anchored heading and description of a service, message or enum
+func (r *markdownPageRenderer) renderHeading(fqn string, description string, options map[string]interface{}) {
+	fmt.Fprintf(r.buf, "### %s%s%s\n\n", anchorTag(fqn), markdownEscape(r.ctx.localName(fqn)), deprecatedBadge(options))
+	if len(description) > 0 {
+		fmt.Fprintf(r.buf, "%s\n\n", description)
+	}
+}
+
+// fieldLabel returns the cardinality of a field: `map`, or its label without the `LABEL_` prefix
+func (r *markdownPageRenderer) fieldLabel(field *Field) string {
+	if entry, found := r.ctx.Messages[field.FullType]; found && entry.IsMapEntry {
+		return "map"
+	}
+	return strings.ToLower(strings.TrimPrefix(field.Label, "LABEL_"))
+}
+
+// fieldTypeLink renders the type of a field, linking to its documentation if possible
+func (r *markdownPageRenderer) fieldTypeLink(field *Field) string {
+	if entry, found := r.ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+		key := r.ctx.Fields[entry.Fields[0]]
+		value := r.ctx.Fields[entry.Fields[1]]
+		return fmt.Sprintf("map\\<%s, %s\\>", r.typeLink(key.FullType), r.typeLink(value.FullType))
+	}
+	return r.typeLink(field.FullType)
+}
+
+// typeLink renders a reference to the type `fqn`, linking to its documentation if it is generated, or to the
+// reference documentation if it is a well-known type
+func (r *markdownPageRenderer) typeLink(fqn string) string {
+	entry, found := r.ctx.Index[fqn]
+	if !found || entry.External {
+		if wellKnown, isWellKnown := wellKnownTypes[fqn]; isWellKnown {
+			return fmt.Sprintf("[`%s`](%s %q)", fqn, wellKnown.URL, wellKnown.JSON)
+		}
+		return "`" + fqn + "`"
+	}
+
+	return fmt.Sprintf("[%s](%s)", markdownEscape(r.ctx.localName(fqn)), r.link(entry.File, fqn))
+}
+
+// link returns a relative link from the current page to the anchor `fqn`, which is declared in the file `file`
+func (r *markdownPageRenderer) link(file string, fqn string) string {
+	target, found := r.pages[r.pageOf(file)]
+	if !found || target == r.page {
+		return "#" + fqn
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(r.page.Filename), target.Filename)
+	if err != nil {
+		rel = target.Filename
+	}
+	return filepath.ToSlash(rel) + "#" + fqn
+}
+
+// localName returns `fqn` relative to the package it is declared in, EG `Outer.Inner` for a nested message
+func (ctx *Context) localName(fqn string) string {
+	entry, found := ctx.Index[fqn]
+	if !found {
+		return fqn
+	}
+
+	file, found := ctx.Files[entry.File]
+	if !found || len(file.Package) == 0 {
+		return fqn
+	}
+	return strings.TrimPrefix(fqn, file.Package+".")
+}
+
+// anchorTag returns an HTML anchor for `fqn`, so it can be linked to regardless of how headings are slugified
+func anchorTag(fqn string) string {
+	return fmt.Sprintf(`<a id="%s"></a>`, fqn)
+}
+
+// deprecatedBadge returns a marker for deprecated objects, and an empty string otherwise
+func deprecatedBadge(options map[string]interface{}) string {
+	if deprecated, _ := options["deprecated"].(bool); deprecated {
+		return " *(deprecated)*"
+	}
+	return ""
+}
+
+// markdownSpecialChars are the characters which have a meaning in inline Markdown
+var markdownSpecialChars = "\\`*_[]<>|"
+
+// markdownEscape escapes inline Markdown syntax in `str`
+func markdownEscape(str string) string {
+	ret := new(strings.Builder)
+	for _, c := range str {
+		if strings.ContainsRune(markdownSpecialChars, c) {
+			ret.WriteRune('\\')
+		}
+		ret.WriteRune(c)
+	}
+	return ret.String()
+}
+
+// markdownTableCell makes multi-line text (such as a description) safe to use in a table cell
+func markdownTableCell(str string) string {
+	str = strings.ReplaceAll(str, "|", "\\|")
+	lines := strings.Split(strings.TrimSpace(str), "\n")
+	return strings.Join(lines, "<br>")
+}
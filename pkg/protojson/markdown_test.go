@@ -0,0 +1,74 @@
+package protojson
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarkdownPageNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		pages  string
+		rename string
+		// pkg is the package to give booking.proto, if not empty
+		pkg  *string
+		want []string
+	}{
+		{
+			name:  "files",
+			pages: MarkdownPagesFile,
+			want:  []string{"booking.md", "extend.md", "index.md", "todo.md", "todo_import.md"},
+		},
+		{
+			name:   "file named index",
+			pages:  MarkdownPagesFile,
+			rename: "index.proto",
+			want:   []string{"extend.md", "index.md", "index.proto.md", "todo.md", "todo_import.md"},
+		},
+		{
+			name:  "packages",
+			pages: MarkdownPagesPackage,
+			want:  []string{"com.pseudomuto.protokit.v1.md", "index.md"},
+		},
+		{
+			name:  "file without a package",
+			pages: MarkdownPagesPackage,
+			pkg:   proto.String(""),
+			want:  []string{"com.pseudomuto.protokit.v1.md", "index.md", "no-package.md"},
+		},
+		{
+			name:  "package named index",
+			pages: MarkdownPagesPackage,
+			pkg:   proto.String("index"),
+			want:  []string{"com.pseudomuto.protokit.v1.md", "index.md", "package-index.md"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := fixtureRequest(t, "format=markdown,markdown_pages="+test.pages)
+			file := fixtureFile(t, req, "booking.proto")
+			if test.pkg != nil {
+				// Moving the whole file to another package keeps its references resolvable
+				file.Package = test.pkg
+				file.GetService()[0].GetMethod()[0].InputType = proto.String(".Booking")
+			}
+			if len(test.rename) > 0 {
+				file.Name = proto.String(test.rename)
+				req.FileToGenerate[0] = test.rename
+			}
+
+			got := make([]string, 0)
+			for name := range generateFixture(t, req) {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got pages %q, want %q", got, test.want)
+			}
+		})
+	}
+}
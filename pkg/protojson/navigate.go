@@ -0,0 +1,150 @@
+package protojson
+
+import (
+	"sort"
+	"strings"
+)
+
+// objectSource is what the navigation helpers need to look objects up. It's implemented by Context, which holds
+// every object, and Reader, which decodes them on demand.
+type objectSource interface {
+	Lookup(fqn string) (interface{}, bool)
+	indexEntry(fqn string) (*IndexEntry, bool)
+	allMethods() []*Method
+}
+
+func (ctx *Context) indexEntry(fqn string) (*IndexEntry, bool) {
+	entry, found := ctx.Index[fqn]
+	return entry, found
+}
+
+func (ctx *Context) allMethods() []*Method {
+	ret := make([]*Method, 0, len(ctx.Methods))
+	for _, method := range ctx.Methods {
+		ret = append(ret, method)
+	}
+	return ret
+}
+
+// Parent returns the object which declares `v`: the message of a field or nested type, the enum of a value, or the
+// service of a method. Top-level services, messages and enums have no parent.
+func (ctx *Context) Parent(v interface{}) (interface{}, bool) {
+	return parentOf(ctx, v)
+}
+
+// Resolve returns the message or enum a type reference refers to, such as `Field.FullType` or `Method.InputType`.
+// Scalar types, and types which aren't in the output (or are only external stubs), aren't resolved.
+func (ctx *Context) Resolve(typeName string) (interface{}, bool) {
+	return resolveType(ctx, typeName)
+}
+
+// MethodsUsing returns the methods which take or return `message`, ordered by FQN
+func (ctx *Context) MethodsUsing(message *Message) []*Method {
+	return methodsUsing(ctx, message)
+}
+
+func parentOf(src objectSource, v interface{}) (interface{}, bool) {
+	fqn := fullNameOf(v)
+	entry, found := src.indexEntry(fqn)
+	if !found {
+		return nil, false
+	}
+
+	parent := parentName(fqn, entry)
+	if len(parent) == 0 {
+		return nil, false
+	}
+	return src.Lookup(parent)
+}
+
+// parentName returns the FQN of the object which declares the indexed object `fqn`, or "" for top-level objects
+func parentName(fqn string, entry *IndexEntry) string {
+	// Methods aren't indexed with their parent, but their FQN is always within their service's
+	if entry.Type == "method" {
+		return fqn[:strings.LastIndex(fqn, ".")]
+	}
+	return entry.Parent
+}
+
+func resolveType(src objectSource, typeName string) (interface{}, bool) {
+	entry, found := src.indexEntry(typeName)
+	if !found || (entry.Type != "message" && entry.Type != "enum") {
+		return nil, false
+	}
+	return src.Lookup(typeName)
+}
+
+func methodsUsing(src objectSource, message *Message) []*Method {
+	ret := make([]*Method, 0)
+	for _, method := range src.allMethods() {
+		if method.InputType == message.FullName || method.OutputType == message.FullName {
+			ret = append(ret, method)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].FullName < ret[j].FullName })
+	return ret
+}
+
+// OptionsOf returns the options set on any object returned by `Lookup`, or nil
+func OptionsOf(v interface{}) map[string]interface{} {
+	switch entity := v.(type) {
+	case *File:
+		return entity.Options
+	case *Service:
+		return entity.Options
+	case *Method:
+		return entity.Options
+	case *Message:
+		return entity.Options
+	case *Field:
+		return entity.Options
+	case *Enum:
+		return entity.Options
+	case *EnumValue:
+		return entity.Options
+	default:
+		return nil
+	}
+}
+
+// DescriptionOf returns the description of any object returned by `Lookup`, or an empty string
+func DescriptionOf(v interface{}) string {
+	switch entity := v.(type) {
+	case *File:
+		return entity.Description
+	case *Service:
+		return entity.Description
+	case *Method:
+		return entity.Description
+	case *Message:
+		return entity.Description
+	case *Field:
+		return entity.Description
+	case *Enum:
+		return entity.Description
+	case *EnumValue:
+		return entity.Description
+	default:
+		return ""
+	}
+}
+
+// fullNameOf returns the FQN of any indexed object, or an empty string
+func fullNameOf(v interface{}) string {
+	switch entity := v.(type) {
+	case *Service:
+		return entity.FullName
+	case *Method:
+		return entity.FullName
+	case *Message:
+		return entity.FullName
+	case *Field:
+		return entity.FullName
+	case *Enum:
+		return entity.FullName
+	case *EnumValue:
+		return entity.FullName
+	default:
+		return ""
+	}
+}
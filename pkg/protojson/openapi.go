@@ -0,0 +1,350 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIOperations are the HTTP methods which OpenAPI can describe
+var openAPIOperations = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// pathVariable is a variable in an `HttpRule` path template, EG `{name=shelves/*}`
+type pathVariable struct {
+	// FieldPath is the path of the request field the variable is bound to, EG `book.name`
+	FieldPath string
+	// Pattern is the segment pattern the variable must match, EG `shelves/*`
+	Pattern string
+}
+
+// parsePathTemplate converts an `HttpRule` path template to an OpenAPI path, returning the variables it contains
+func parsePathTemplate(template string) (string, []*pathVariable) {
+	path := new(strings.Builder)
+	variables := make([]*pathVariable, 0)
+
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			path.WriteString(template)
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			path.WriteString(template)
+			break
+		}
+		end += start
+
+		fieldPath, pattern, _ := strings.Cut(template[start+1:end], "=")
+		variables = append(variables, &pathVariable{FieldPath: fieldPath, Pattern: pattern})
+
+		path.WriteString(template[:start])
+		path.WriteString("{" + fieldPath + "}")
+		template = template[end+1:]
+	}
+
+	return path.String(), variables
+}
+
+// openAPIBuilder builds an OpenAPI document for the services in a context
+type openAPIBuilder struct {
+	ctx     *Context
+	schemas *jsonSchemaBuilder
+	// usedOperationIDs tracks operation IDs, which must be unique across the document
+	usedOperationIDs map[string]bool
+	warn             func(format string, args ...interface{})
+}
+
+// generateOpenAPI builds an OpenAPI 3.1 document describing every method with a `google.api.http` binding.
+// The document is written as YAML if `params.OpenAPI` ends in `.yaml` or `.yml`, and as JSON otherwise.
+func generateOpenAPI(ctx *Context, params *Options) (*plugin_go.CodeGeneratorResponse_File, error) {
+	builder := &openAPIBuilder{
+		ctx: ctx,
+		schemas: &jsonSchemaBuilder{
+			ctx: ctx,
+			ref: func(fqn string) string { return "#/components/schemas/" + fqn },
+		},
+		usedOperationIDs: make(map[string]bool),
+		warn:             params.warn,
+	}
+
+	order := ctx.declaredOrder()
+
+	tags := make([]interface{}, 0)
+	paths := newOrderedMap()
+	for _, serviceName := range order.Services {
+		service := ctx.Services[serviceName]
+
+		bound := false
+		for _, methodName := range service.Methods {
+			method := ctx.Methods[methodName]
+			for _, rule := range method.HTTPRules {
+				builder.addOperation(paths, service, method, rule)
+				bound = true
+			}
+		}
+
+		// Services without any REST bindings aren't part of the document
+		if !bound {
+			continue
+		}
+
+		tag := schemaOf("name", service.Name)
+		if len(service.Description) > 0 {
+			tag.Set("description", service.Description)
+		}
+		tags = append(tags, tag)
+	}
+
+	components := newOrderedMap()
+	for _, fqn := range order.Messages {
+		if !ctx.Messages[fqn].IsMapEntry {
+			components.Set(fqn, builder.schemas.messageSchema(ctx.Messages[fqn]))
+		}
+	}
+
+	title := params.OpenAPITitle
+	if len(title) == 0 {
+		title = strings.Join(ctx.packages(), ", ")
+	}
+
+	document := schemaOf(
+		"openapi", "3.1.0",
+		"info", schemaOf("title", title, "version", params.OpenAPIVersion),
+		"tags", tags,
+		"paths", paths,
+		"components", schemaOf("schemas", components),
+	)
+
+	encoded := new(bytes.Buffer)
+	encoder := json.NewEncoder(encoded)
+	encoder.SetIndent("", params.Indent)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+
+	content := encoded.String()
+	if strings.HasSuffix(params.OpenAPI, ".yaml") || strings.HasSuffix(params.OpenAPI, ".yml") {
+		root := new(yaml.Node)
+		if err := yaml.Unmarshal(encoded.Bytes(), root); err != nil {
+			return nil, err
+		}
+		restyleYAML(root)
+
+		out := new(bytes.Buffer)
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(yamlIndent(params.Indent))
+		if err := encoder.Encode(root); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		content = out.String()
+	}
+
+	return &plugin_go.CodeGeneratorResponse_File{
+		Name:    proto.String(params.OpenAPI),
+		Content: proto.String(content),
+	}, nil
+}
+
+// packages returns the distinct packages of the files in the context, sorted
+func (ctx *Context) packages() []string {
+	seen := make(map[string]bool)
+	ret := make([]string, 0)
+
+	for _, file := range ctx.Files {
+		if !seen[file.Package] {
+			seen[file.Package] = true
+			ret = append(ret, file.Package)
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// addOperation adds the operation for a single HTTP binding of `method` to `paths`
+func (b *openAPIBuilder) addOperation(paths *orderedMap, service *Service, method *Method, rule *HTTPRule) {
+	verb := strings.ToLower(rule.Method)
+	if !openAPIOperations[verb] {
+		b.warn("skipping binding of %s: OpenAPI can't describe HTTP method %s", method.FullName, rule.Method)
+		return
+	}
+
+	path, variables := parsePathTemplate(rule.Path)
+
+	operation := newOrderedMap()
+	operation.Set("tags", []string{service.Name})
+	operation.Set("operationId", b.operationID(service.Name+"_"+method.Name))
+	if len(method.Description) > 0 {
+		operation.Set("description", method.Description)
+	}
+	if deprecated, _ := method.Options["deprecated"].(bool); deprecated {
+		operation.Set("deprecated", true)
+	}
+
+	// Fields bound to the path or the body can't also be query parameters
+	bound := make(map[string]bool)
+	parameters := make([]interface{}, 0)
+
+	for _, variable := range variables {
+		bound[variable.FieldPath] = true
+
+		parameter := schemaOf("name", variable.FieldPath, "in", "path", "required", true)
+		schema := schemaOf("type", "string")
+		if field := b.resolveFieldPath(method.InputType, variable.FieldPath); field != nil {
+			schema = b.schemas.typeSchema(field)
+			if len(field.Description) > 0 {
+				parameter.Set("description", field.Description)
+			}
+		}
+		if len(variable.Pattern) > 0 {
+			// Multi-segment patterns can't be expressed in OpenAPI, so just document them
+			schema.Set("description", "Must match the path pattern `"+variable.Pattern+"`")
+		}
+		parameter.Set("schema", schema)
+
+		parameters = append(parameters, parameter)
+	}
+
+	var bodySchema *orderedMap
+	switch rule.Body {
+	case "":
+	case "*":
+		bodySchema = b.schemas.messageTypeSchema(method.InputType)
+		// Every field which isn't bound to the path is part of the body
+		for _, fqn := range b.messageFields(method.InputType) {
+			bound[b.ctx.Fields[fqn].Name] = true
+		}
+	default:
+		bound[rule.Body] = true
+		if field := b.resolveFieldPath(method.InputType, rule.Body); field != nil {
+			bodySchema = b.schemas.fieldSchema(field)
+		}
+	}
+
+	parameters = append(parameters, b.queryParameters(method.InputType, bound)...)
+	if len(parameters) > 0 {
+		operation.Set("parameters", parameters)
+	}
+	if bodySchema != nil {
+		operation.Set("requestBody", schemaOf(
+			"required", true,
+			"content", schemaOf("application/json", schemaOf("schema", bodySchema)),
+		))
+	}
+
+	responseSchema := b.schemas.messageTypeSchema(method.OutputType)
+	if len(rule.ResponseBody) > 0 {
+		if field := b.resolveFieldPath(method.OutputType, rule.ResponseBody); field != nil {
+			responseSchema = b.schemas.fieldSchema(field)
+		}
+	}
+	operation.Set("responses", schemaOf(
+		"200", schemaOf(
+			"description", "A successful response.",
+			"content", schemaOf("application/json", schemaOf("schema", responseSchema)),
+		),
+	))
+
+	operations, found := paths.Get(path)
+	if !found {
+		operations = newOrderedMap()
+		paths.Set(path, operations)
+	}
+	operations.(*orderedMap).Set(verb, operation)
+}
+
+// queryParameters builds query parameters for the scalar fields of the message `fqn` which aren't in `bound`.
+// Message fields aren't expanded into query parameters, except for well-known types with a string representation.
+func (b *openAPIBuilder) queryParameters(fqn string, bound map[string]bool) []interface{} {
+	ret := make([]interface{}, 0)
+
+	for _, fieldName := range b.messageFields(fqn) {
+		field := b.ctx.Fields[fieldName]
+		if bound[field.Name] {
+			continue
+		}
+
+		if _, isMessage := b.ctx.Messages[field.FullType]; isMessage {
+			continue
+		}
+		if _, isWellKnown := wellKnownTypes[field.FullType]; !isWellKnown && field.Descriptor != nil &&
+			field.Descriptor.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			continue
+		}
+
+		parameter := schemaOf("name", jsonFieldName(field), "in", "query")
+		if len(field.Description) > 0 {
+			parameter.Set("description", field.Description)
+		}
+		if deprecated, _ := field.Options["deprecated"].(bool); deprecated {
+			parameter.Set("deprecated", true)
+		}
+
+		schema := b.schemas.typeSchema(field)
+		if field.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED.String() {
+			schema = schemaOf("type", "array", "items", schema)
+		}
+		parameter.Set("schema", schema)
+
+		ret = append(ret, parameter)
+	}
+
+	return ret
+}
+
+// messageFields returns the fields of the message `fqn`, or nothing if it isn't in the context
+func (b *openAPIBuilder) messageFields(fqn string) []string {
+	if message, found := b.ctx.Messages[fqn]; found {
+		return message.Fields
+	}
+	return nil
+}
+
+// resolveFieldPath finds the field referred to by a dot-separated path of field names, starting from the message `fqn`
+func (b *openAPIBuilder) resolveFieldPath(fqn string, fieldPath string) *Field {
+	var field *Field
+
+	for _, name := range strings.Split(fieldPath, ".") {
+		if field != nil {
+			fqn = field.FullType
+		}
+
+		field = nil
+		for _, candidate := range b.messageFields(fqn) {
+			if b.ctx.Fields[candidate].Name == name {
+				field = b.ctx.Fields[candidate]
+				break
+			}
+		}
+
+		if field == nil {
+			return nil
+		}
+	}
+
+	return field
+}
+
+// operationID returns `id`, with a numeric suffix if it has already been used
+func (b *openAPIBuilder) operationID(id string) string {
+	ret := id
+	for i := 1; b.usedOperationIDs[ret]; i++ {
+		ret = fmt.Sprintf("%s_%d", id, i)
+	}
+	b.usedOperationIDs[ret] = true
+	return ret
+}
@@ -0,0 +1,213 @@
+package protojson
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// encodeFields encodes length-delimited fields, given as pairs of a field number and a string or encoded message
+func encodeFields(fields ...interface{}) []byte {
+	ret := make([]byte, 0)
+	for i := 0; i < len(fields); i += 2 {
+		ret = protowire.AppendTag(ret, protowire.Number(fields[i].(int)), protowire.BytesType)
+		switch value := fields[i+1].(type) {
+		case string:
+			ret = protowire.AppendString(ret, value)
+		case []byte:
+			ret = protowire.AppendBytes(ret, value)
+		}
+	}
+	return ret
+}
+
+// setHTTPRule sets the `google.api.http` option of a method to an encoded `HttpRule`, as `protoc` does when
+// `google/api/annotations.proto` isn't among the files it knows the extensions of
+func setHTTPRule(method *descriptorpb.MethodDescriptorProto, rule []byte) {
+	if method.Options == nil {
+		method.Options = new(descriptorpb.MethodOptions)
+	}
+	// An unrelated unknown option comes first, to be skipped
+	raw := protowire.AppendTag(nil, 50001, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, 1)
+	raw = append(raw, encodeFields(httpRuleExtension, rule)...)
+	method.Options.ProtoReflect().SetUnknown(raw)
+}
+
+func TestParseHTTPRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule []byte
+		want []*HTTPRule
+	}{
+		{
+			name: "get",
+			rule: encodeFields(httpRuleGet, "/v1/{name=lists/*}"),
+			want: []*HTTPRule{{Method: "GET", Path: "/v1/{name=lists/*}"}},
+		},
+		{
+			name: "body and additional bindings",
+			rule: encodeFields(
+				httpRulePost, "/v1/lists",
+				httpRuleBody, "*",
+				httpRuleAdditionalBindings, encodeFields(httpRulePut, "/v1/lists/{name}", httpRuleBody, "list"),
+				httpRuleResponseBody, "list",
+			),
+			want: []*HTTPRule{
+				{Method: "POST", Path: "/v1/lists", Body: "*", ResponseBody: "list"},
+				{Method: "PUT", Path: "/v1/lists/{name}", Body: "list"},
+			},
+		},
+		{
+			name: "custom",
+			rule: encodeFields(httpRuleCustom, encodeFields(customHTTPPatternKind, "head", customHTTPPatternPath, "/v1/lists")),
+			want: []*HTTPRule{{Method: "HEAD", Path: "/v1/lists"}},
+		},
+		{
+			name: "only additional bindings",
+			rule: encodeFields(httpRuleAdditionalBindings, encodeFields(httpRuleDelete, "/v1/{name}")),
+			want: []*HTTPRule{{Method: "DELETE", Path: "/v1/{name}"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := &descriptorpb.MethodDescriptorProto{}
+			setHTTPRule(method, test.rule)
+			if got := parseHTTPRules(method.GetOptions()); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got rules %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if got := parseHTTPRules(&descriptorpb.MethodOptions{}); got != nil {
+		t.Errorf("got rules %+v for a method without any", got)
+	}
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	path, variables := parsePathTemplate("/v1/{parent=shelves/*}/books/{book.id}:publish")
+	if path != "/v1/{parent}/books/{book.id}:publish" {
+		t.Errorf("got path %q", path)
+	}
+	want := []*pathVariable{{FieldPath: "parent", Pattern: "shelves/*"}, {FieldPath: "book.id"}}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("got variables %+v, want %+v", variables, want)
+	}
+}
+
+// findRefs returns every `$ref` in a decoded JSON document
+func findRefs(v interface{}) []string {
+	ret := make([]string, 0)
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if ref, isString := child.(string); key == "$ref" && isString {
+				ret = append(ret, ref)
+			}
+			ret = append(ret, findRefs(child)...)
+		}
+	case []interface{}:
+		for _, child := range value {
+			ret = append(ret, findRefs(child)...)
+		}
+	}
+	return ret
+}
+
+// jsonPath returns the value at a path of keys in a decoded JSON document, or nil
+func jsonPath(v interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, isObject := v.(map[string]interface{})
+		if !isObject {
+			return nil
+		}
+		v = object[key]
+	}
+	return v
+}
+
+func TestOpenAPI(t *testing.T) {
+	// todo_import.proto isn't generated, so its messages are external
+	req := fixtureRequest(t, "openapi=openapi.json", "booking.proto", "extend.proto", "todo.proto")
+
+	todo := fixtureFile(t, req, "todo.proto").GetService()[0]
+	setHTTPRule(todo.GetMethod()[0], encodeFields(httpRulePost, "/v1/lists", httpRuleBody, "*"))
+	setHTTPRule(todo.GetMethod()[1], encodeFields(httpRulePost, "/v1/lists/{list_id}/items", httpRuleBody, "title"))
+	todo.GetMethod()[1].OutputType = proto.String(".com.pseudomuto.protokit.v1.ListItemDetails")
+
+	booking := fixtureFile(t, req, "booking.proto").GetService()[0].GetMethod()[0]
+	setHTTPRule(booking, encodeFields(httpRulePost, "/v1/bookings", httpRuleBody, "*"))
+	booking.InputType = proto.String(".google.protobuf.Any")
+	booking.OutputType = proto.String(".google.protobuf.Timestamp")
+
+	var document interface{}
+	if err := json.Unmarshal([]byte(generateFixture(t, req)["openapi.json"]), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every reference resolves, including those to request and response types which have no schema of their own
+	for _, ref := range findRefs(document) {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if jsonPath(document, "components", "schemas", name) == nil {
+			t.Errorf("%s doesn't resolve", ref)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path []string
+		want interface{}
+	}{
+		{
+			name: "request body",
+			path: []string{"paths", "/v1/lists", "post", "requestBody", "content", "application/json", "schema", "$ref"},
+			want: "#/components/schemas/com.pseudomuto.protokit.v1.CreateListRequest",
+		},
+		{
+			name: "response",
+			path: []string{"paths", "/v1/lists", "post", "responses", "200", "content", "application/json", "schema", "$ref"},
+			want: "#/components/schemas/com.pseudomuto.protokit.v1.CreateListResponse",
+		},
+		{
+			name: "external response",
+			path: []string{"paths", "/v1/lists/{list_id}/items", "post", "responses", "200", "content", "application/json", "schema", "type"},
+			want: "object",
+		},
+		{
+			name: "field body",
+			path: []string{"paths", "/v1/lists/{list_id}/items", "post", "requestBody", "content", "application/json", "schema", "type"},
+			want: "string",
+		},
+		{
+			name: "well-known request",
+			path: []string{"paths", "/v1/bookings", "post", "requestBody", "content", "application/json", "schema", "type"},
+			want: "object",
+		},
+		{
+			name: "well-known response",
+			path: []string{"paths", "/v1/bookings", "post", "responses", "200", "content", "application/json", "schema", "format"},
+			want: "date-time",
+		},
+	}
+	for _, test := range tests {
+		if got := jsonPath(document, test.path...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// Fields bound to the path or body aren't query parameters
+	parameters, _ := jsonPath(document, "paths", "/v1/lists/{list_id}/items", "post", "parameters").([]interface{})
+	got := make([]string, 0)
+	for _, parameter := range parameters {
+		got = append(got, jsonPath(parameter, "in").(string)+" "+jsonPath(parameter, "name").(string))
+	}
+	if want := []string{"path list_id", "query completed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got parameters %q, want %q", got, want)
+	}
+}
@@ -1,7 +1,6 @@
-package main
+package protojson
 
 import (
-	"fmt"
 	"github.com/pseudomuto/protokit"
 	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -73,28 +72,28 @@ func parseAllCustomOptionDefinitions(files []*protokit.FileDescriptor) *CustomOp
 }
 
 // parseAllCustomOptionValues parses all the values of the custom options set on files/messages/fields/services/etc.
-// and updates `context` with the parsed values
-func parseAllCustomOptionValues(context *Context) {
+// and updates `context` with the parsed values. Options which can't be parsed are reported to `warn`.
+func parseAllCustomOptionValues(context *Context, warn func(format string, args ...interface{})) {
 	for _, file := range context.Files {
-		file.Options = parseFileOptions(file.Descriptor, context)
+		file.Options = parseFileOptions(file.Descriptor, context, warn)
 	}
 	for _, message := range context.Messages {
-		message.Options = parseMessageOptions(message.Descriptor, context)
+		message.Options = parseMessageOptions(message.Descriptor, context, warn)
 	}
 	for _, field := range context.Fields {
-		field.Options = parseFieldOptions(field.Descriptor, context)
+		field.Options = parseFieldOptions(field.Descriptor, context, warn)
 	}
 	for _, enum := range context.Enums {
-		enum.Options = parseEnumOptions(enum.Descriptor, context)
+		enum.Options = parseEnumOptions(enum.Descriptor, context, warn)
 	}
 	for _, enumVal := range context.EnumValues {
-		enumVal.Options = parseEnumValueOptions(enumVal.Descriptor, context)
+		enumVal.Options = parseEnumValueOptions(enumVal.Descriptor, context, warn)
 	}
 	for _, service := range context.Services {
-		service.Options = parseServiceOptions(service.Descriptor, context)
+		service.Options = parseServiceOptions(service.Descriptor, context, warn)
 	}
 	for _, method := range context.Methods {
-		method.Options = parseMethodOptions(method.Descriptor, context)
+		method.Options = parseMethodOptions(method.Descriptor, context, warn)
 	}
 }
 
@@ -113,7 +112,7 @@ func parseCustomOption(ext *protokit.ExtensionDescriptor) *CustomOptionDef {
 	return ret
 }
 
-func parseRawOptions(entityName string, raw protoreflect.RawFields, optionsDB *map[int32]*CustomOptionDef) map[string]interface{} {
+func parseRawOptions(entityName string, raw protoreflect.RawFields, optionsDB *map[int32]*CustomOptionDef, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	size := len(raw)
@@ -128,7 +127,7 @@ func parseRawOptions(entityName string, raw protoreflect.RawFields, optionsDB *m
 		// Read tag
 		optionIndex, wireType, length := protowire.ConsumeTag(raw[consumed:])
 		if length < 0 {
-			fmt.Printf("FAILED PARSING OPTIONS FOR ENTITY %s: %v", entityName, raw)
+			warn("failed to parse the options of %s: %v", entityName, protowire.ParseError(length))
 			return nil
 		}
 		consumed += length
@@ -136,7 +135,14 @@ func parseRawOptions(entityName string, raw protoreflect.RawFields, optionsDB *m
 		// Find option
 		optionDef, found := (*optionsDB)[int32(optionIndex)]
 
+		// Skip the values of options which aren't defined in the request, EG `google.api.http`
 		if !found {
+			valLen := protowire.ConsumeFieldValue(optionIndex, wireType, raw[consumed:])
+			if valLen < 0 {
+				warn("failed to parse the options of %s: %v", entityName, protowire.ParseError(valLen))
+				return nil
+			}
+			consumed += valLen
 			continue
 		}
 
@@ -231,7 +237,7 @@ func parseRawOptions(entityName string, raw protoreflect.RawFields, optionsDB *m
 
 // parseFileOptions parses options on a file,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseFileOptions(file *protokit.FileDescriptor, context *Context) map[string]interface{} {
+func parseFileOptions(file *protokit.FileDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := file.GetOptions()
@@ -246,7 +252,7 @@ func parseFileOptions(file *protokit.FileDescriptor, context *Context) map[strin
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(file.GetName(), raw, &context.CustomOptions.FileOptions) {
+	for k, v := range parseRawOptions(file.GetName(), raw, &context.CustomOptions.FileOptions, warn) {
 		ret[k] = v
 	}
 
@@ -255,7 +261,7 @@ func parseFileOptions(file *protokit.FileDescriptor, context *Context) map[strin
 
 // parseMessageOptions parses options on a message,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseMessageOptions(message *protokit.Descriptor, context *Context) map[string]interface{} {
+func parseMessageOptions(message *protokit.Descriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := message.GetOptions()
@@ -270,7 +276,7 @@ func parseMessageOptions(message *protokit.Descriptor, context *Context) map[str
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(message.GetFullName(), raw, &context.CustomOptions.MessageOptions) {
+	for k, v := range parseRawOptions(message.GetFullName(), raw, &context.CustomOptions.MessageOptions, warn) {
 		ret[k] = v
 	}
 
@@ -279,7 +285,7 @@ func parseMessageOptions(message *protokit.Descriptor, context *Context) map[str
 
 // parseFieldOptions parses options on a field,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseFieldOptions(field *protokit.FieldDescriptor, context *Context) map[string]interface{} {
+func parseFieldOptions(field *protokit.FieldDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := field.GetOptions()
@@ -294,7 +300,7 @@ func parseFieldOptions(field *protokit.FieldDescriptor, context *Context) map[st
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(field.GetFullName(), raw, &context.CustomOptions.FieldOptions) {
+	for k, v := range parseRawOptions(field.GetFullName(), raw, &context.CustomOptions.FieldOptions, warn) {
 		ret[k] = v
 	}
 
@@ -303,7 +309,7 @@ func parseFieldOptions(field *protokit.FieldDescriptor, context *Context) map[st
 
 // parseEnumOptions parses options on an enum,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseEnumOptions(enum *protokit.EnumDescriptor, context *Context) map[string]interface{} {
+func parseEnumOptions(enum *protokit.EnumDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := enum.GetOptions()
@@ -318,7 +324,7 @@ func parseEnumOptions(enum *protokit.EnumDescriptor, context *Context) map[strin
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(enum.GetFullName(), raw, &context.CustomOptions.EnumOptions) {
+	for k, v := range parseRawOptions(enum.GetFullName(), raw, &context.CustomOptions.EnumOptions, warn) {
 		ret[k] = v
 	}
 
@@ -327,7 +333,7 @@ func parseEnumOptions(enum *protokit.EnumDescriptor, context *Context) map[strin
 
 // parseEnumValueOptions parses options on an enum value,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseEnumValueOptions(enumVal *protokit.EnumValueDescriptor, context *Context) map[string]interface{} {
+func parseEnumValueOptions(enumVal *protokit.EnumValueDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := enumVal.GetOptions()
@@ -342,7 +348,7 @@ func parseEnumValueOptions(enumVal *protokit.EnumValueDescriptor, context *Conte
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(enumVal.GetFullName(), raw, &context.CustomOptions.EnumValueOptions) {
+	for k, v := range parseRawOptions(enumVal.GetFullName(), raw, &context.CustomOptions.EnumValueOptions, warn) {
 		ret[k] = v
 	}
 
@@ -351,7 +357,7 @@ func parseEnumValueOptions(enumVal *protokit.EnumValueDescriptor, context *Conte
 
 // parseServiceOptions parses options on a service,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseServiceOptions(service *protokit.ServiceDescriptor, context *Context) map[string]interface{} {
+func parseServiceOptions(service *protokit.ServiceDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := service.GetOptions()
@@ -366,7 +372,7 @@ func parseServiceOptions(service *protokit.ServiceDescriptor, context *Context)
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(service.GetFullName(), raw, &context.CustomOptions.ServiceOptions) {
+	for k, v := range parseRawOptions(service.GetFullName(), raw, &context.CustomOptions.ServiceOptions, warn) {
 		ret[k] = v
 	}
 
@@ -375,7 +381,7 @@ func parseServiceOptions(service *protokit.ServiceDescriptor, context *Context)
 
 // parseMethodOptions parses options on a method,
 // mapping them to CustomOptions which were discovered during `parseAllCustomOptionDefinitions`
-func parseMethodOptions(method *protokit.MethodDescriptor, context *Context) map[string]interface{} {
+func parseMethodOptions(method *protokit.MethodDescriptor, context *Context, warn func(format string, args ...interface{})) map[string]interface{} {
 	ret := make(map[string]interface{})
 
 	options := method.GetOptions()
@@ -390,7 +396,7 @@ func parseMethodOptions(method *protokit.MethodDescriptor, context *Context) map
 	raw := options.ProtoReflect().GetUnknown()
 
 	// Parse the options from the raw bytes of the message and store them in ret
-	for k, v := range parseRawOptions(method.GetFullName(), raw, &context.CustomOptions.MethodOptions) {
+	for k, v := range parseRawOptions(method.GetFullName(), raw, &context.CustomOptions.MethodOptions, warn) {
 		ret[k] = v
 	}
 
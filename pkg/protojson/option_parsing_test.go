@@ -0,0 +1,45 @@
+package protojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestParseCustomOptions(t *testing.T) {
+	req := fixtureRequest(t, "")
+	methods := fixtureFile(t, req, "todo.proto").GetService()[0].GetMethod()
+
+	// An option which isn't defined in the request comes before one which is
+	raw := encodeFields(httpRuleExtension, encodeFields(httpRuleGet, "/v1/lists"))
+	raw = protowire.AppendTag(raw, 20000, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, 1)
+	methods[0].Options = new(descriptorpb.MethodOptions)
+	methods[0].Options.ProtoReflect().SetUnknown(raw)
+
+	// A truncated tag
+	methods[1].Options = new(descriptorpb.MethodOptions)
+	methods[1].Options.ProtoReflect().SetUnknown([]byte{0x80})
+
+	warnings := make([]string, 0)
+	opts := DefaultOptions()
+	opts.Warn = func(message string) { warnings = append(warnings, message) }
+	ctx, err := Build(req, *opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{fixturePackage + "extend_method": true}
+	if got := ctx.Methods[fixturePackage+"Todo.CreateList"].Options; !reflect.DeepEqual(got, want) {
+		t.Errorf("got options %v, want %v", got, want)
+	}
+	if got := ctx.Methods[fixturePackage+"Todo.AddItem"].Options; len(got) > 0 {
+		t.Errorf("got options %v from malformed options", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], fixturePackage+"Todo.AddItem") {
+		t.Errorf("got warnings %q", warnings)
+	}
+}
@@ -0,0 +1,227 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Layouts for the collections in the output, selected with the `collections` parameter
+const (
+	// CollectionsSorted emits collections as objects keyed by FQN, with keys sorted alphabetically (the default)
+	CollectionsSorted = "sorted"
+	// CollectionsDeclared emits collections as objects keyed by FQN, with keys in declaration order
+	CollectionsDeclared = "declared"
+	// CollectionsArray emits collections as arrays in declaration order
+	CollectionsArray = "array"
+)
+
+// orderedMap is a JSON object which preserves the order its keys were added in
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{
+		keys:   make([]string, 0),
+		values: make(map[string]interface{}),
+	}
+}
+
+// Set sets `key` to `value`, appending `key` if it isn't already present
+func (m *orderedMap) Set(key string, value interface{}) {
+	if _, found := m.values[key]; !found {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Keys returns the keys of the map, in order
+func (m *orderedMap) Keys() []string {
+	return m.keys
+}
+
+// Get returns the value stored at `key`
+func (m *orderedMap) Get(key string) (interface{}, bool) {
+	value, found := m.values[key]
+	return value, found
+}
+
+// Delete removes `keys` from the map, if they are present
+func (m *orderedMap) Delete(keys ...string) {
+	for _, key := range keys {
+		if _, found := m.values[key]; !found {
+			continue
+		}
+
+		delete(m.values, key)
+		for i, existing := range m.keys {
+			if existing == key {
+				m.keys = append(m.keys[:i], m.keys[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// OrderedFiles returns every file in the context, in the order they were passed to the plugin
+func (ctx *Context) OrderedFiles() []*File {
+	files := make([]*File, 0, len(ctx.Files))
+	for _, file := range ctx.Files {
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].DeclarationIndex < files[j].DeclarationIndex
+	})
+
+	return files
+}
+
+// declarationOrder returns the keys of `collection` in declaration order.
+// `declared` extracts the FQNs a file declares in that collection; any keys not declared by a file are
+// appended afterwards, sorted.
+func declarationOrder[T any](ctx *Context, collection map[string]T, declared func(file *File) []string) []string {
+	keys := make([]string, 0, len(collection))
+	seen := make(map[string]bool)
+
+	for _, file := range ctx.OrderedFiles() {
+		for _, fqn := range declared(file) {
+			if _, found := collection[fqn]; found && !seen[fqn] {
+				keys = append(keys, fqn)
+				seen[fqn] = true
+			}
+		}
+	}
+
+	rest := make([]string, 0)
+	for fqn := range collection {
+		if !seen[fqn] {
+			rest = append(rest, fqn)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// orderCollection lays out `collection` according to `layout`, using `keys` as the declaration order
+func orderCollection[T any](collection map[string]T, keys []string, layout string) interface{} {
+	if layout == CollectionsArray {
+		ret := make([]T, 0, len(keys))
+		for _, key := range keys {
+			ret = append(ret, collection[key])
+		}
+		return ret
+	}
+
+	ret := newOrderedMap()
+	for _, key := range keys {
+		ret.Set(key, collection[key])
+	}
+	return ret
+}
+
+// collectionOrder holds the keys of every collection in a context, in declaration order
+type collectionOrder struct {
+	Files      []string
+	Services   []string
+	Methods    []string
+	Messages   []string
+	Fields     []string
+	Enums      []string
+	EnumValues []string
+}
+
+// declaredOrder returns the keys of every collection in `ctx`, in declaration order
+func (ctx *Context) declaredOrder() *collectionOrder {
+	files := make([]string, 0, len(ctx.Files))
+	for _, file := range ctx.OrderedFiles() {
+		files = append(files, file.Name)
+	}
+
+	return &collectionOrder{
+		Files:      files,
+		Services:   declarationOrder(ctx, ctx.Services, func(file *File) []string { return file.Services }),
+		Methods:    declarationOrder(ctx, ctx.Methods, func(file *File) []string { return file.Methods }),
+		Messages:   declarationOrder(ctx, ctx.Messages, func(file *File) []string { return file.Messages }),
+		Fields:     declarationOrder(ctx, ctx.Fields, func(file *File) []string { return file.Fields }),
+		Enums:      declarationOrder(ctx, ctx.Enums, func(file *File) []string { return file.Enums }),
+		EnumValues: declarationOrder(ctx, ctx.EnumValues, func(file *File) []string { return file.EnumValues }),
+	}
+}
+
+// orderedContext builds the output document for `ctx` with its collections laid out according to `layout`.
+// With the default layout, the context is returned as-is, since `encoding/json` already sorts map keys.
+func orderedContext(ctx *Context, layout string) interface{} {
+	if layout == CollectionsSorted {
+		return ctx
+	}
+
+	order := ctx.declaredOrder()
+
+	// The index is always an object, since it's used for lookups
+	index := newOrderedMap()
+	for _, keys := range [][]string{order.Services, order.Methods, order.Messages, order.Fields, order.Enums, order.EnumValues} {
+		for _, fqn := range keys {
+			if entry, found := ctx.Index[fqn]; found {
+				index.Set(fqn, entry)
+			}
+		}
+	}
+	for _, fqn := range declarationOrder(ctx, ctx.Index, func(file *File) []string { return nil }) {
+		if _, found := index.Get(fqn); !found {
+			index.Set(fqn, ctx.Index[fqn])
+		}
+	}
+
+	return &struct {
+		Meta       *Meta       `json:"meta"`
+		Index      *orderedMap `json:"index"`
+		Files      interface{} `json:"files"`
+		Services   interface{} `json:"services"`
+		Methods    interface{} `json:"methods"`
+		Messages   interface{} `json:"messages"`
+		Fields     interface{} `json:"fields"`
+		Enums      interface{} `json:"enums"`
+		EnumValues interface{} `json:"enum_values"`
+	}{
+		Meta:       ctx.Meta,
+		Index:      index,
+		Files:      orderCollection(ctx.Files, order.Files, layout),
+		Services:   orderCollection(ctx.Services, order.Services, layout),
+		Methods:    orderCollection(ctx.Methods, order.Methods, layout),
+		Messages:   orderCollection(ctx.Messages, order.Messages, layout),
+		Fields:     orderCollection(ctx.Fields, order.Fields, layout),
+		Enums:      orderCollection(ctx.Enums, order.Enums, layout),
+		EnumValues: orderCollection(ctx.EnumValues, order.EnumValues, layout),
+	}
+}
@@ -0,0 +1,250 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// outputStruct describes a struct in the output document, as it is encoded to JSON
+type outputStruct struct {
+	Name   string
+	Fields []*outputField
+}
+
+// outputField describes a field of a struct in the output document
+type outputField struct {
+	Name     string
+	Type     reflect.Type
+	Optional bool
+	// Const is the only value the field can have, if it has one
+	Const string
+}
+
+// outputCollectionTypes are the structs of each collection of the output document, named as in
+// `outputSchemaChanges`; the top-level document is the "" collection
+var outputCollectionTypes = map[reflect.Type]string{
+	reflect.TypeOf(Context{}):    "",
+	reflect.TypeOf(IndexEntry{}): "index",
+	reflect.TypeOf(File{}):       "files",
+	reflect.TypeOf(Service{}):    "services",
+	reflect.TypeOf(Method{}):     "methods",
+	reflect.TypeOf(Message{}):    "messages",
+	reflect.TypeOf(Field{}):      "fields",
+	reflect.TypeOf(Enum{}):       "enums",
+	reflect.TypeOf(EnumValue{}):  "enum_values",
+}
+
+// outputStructs describes every struct reachable from Context, in the order they are first referenced.
+// The descriptions are built by reflection, so they can't drift from the structs which are actually encoded.
+//
+// If `layout` is CollectionsArray, the collections of the Context are described as arrays. Keys added to the output
+// format after `version` are left out, like they are from the output (see `downgradeDocument`).
+func outputStructs(layout string, version string) []*outputStruct {
+	ret := make([]*outputStruct, 0)
+	seen := make(map[reflect.Type]bool)
+	removed := keysAddedAfter(version)
+
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+
+		described := &outputStruct{Name: t.Name(), Fields: make([]*outputField, 0)}
+		ret = append(ret, described)
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if len(name) == 0 {
+				name = field.Name
+			}
+			if collection, found := outputCollectionTypes[t]; found && containsString(removed[collection], name) {
+				continue
+			}
+
+			fieldType := field.Type
+			if t == reflect.TypeOf(Context{}) && layout == CollectionsArray && fieldType.Kind() == reflect.Map && name != "index" {
+				fieldType = reflect.SliceOf(fieldType.Elem())
+			}
+
+			described.Fields = append(described.Fields, &outputField{
+				Name:     name,
+				Type:     fieldType,
+				Optional: strings.Contains(opts, "omitempty"),
+			})
+
+			// The header describes the version it was written in
+			if t == reflect.TypeOf(Meta{}) && name == "schema_version" {
+				described.Fields[len(described.Fields)-1].Const = version
+			}
+		}
+
+		for _, field := range described.Fields {
+			visit(field.Type)
+		}
+	}
+
+	visit(reflect.TypeOf(Context{}))
+	return ret
+}
+
+// generateOutputTypeScript generates TypeScript declarations describing the output document, in version `version`
+// of the output format
+func generateOutputTypeScript(layout string, version string) string {
+	buf := new(strings.Builder)
+	buf.WriteString("// Type declarations for the output of protoc-gen-json.\n")
+	fmt.Fprintf(buf, "// Describes schema_version %s of the output format.\n", version)
+	buf.WriteString("// Generated by protoc-gen-json. DO NOT EDIT.\n")
+
+	for _, described := range outputStructs(layout, version) {
+		fmt.Fprintf(buf, "\nexport interface %s {\n", described.Name)
+		for _, field := range described.Fields {
+			optional := ""
+			if field.Optional {
+				optional = "?"
+			}
+			fieldType := typeScriptType(field.Type)
+			if len(field.Const) > 0 {
+				fieldType = fmt.Sprintf("%q", field.Const)
+			}
+			fmt.Fprintf(buf, "  %s%s: %s;\n", field.Name, optional, fieldType)
+		}
+		buf.WriteString("}\n")
+	}
+
+	return buf.String()
+}
+
+// typeScriptType returns the TypeScript equivalent of the JSON encoding of `t`
+func typeScriptType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeScriptType(t.Elem())
+	case reflect.Struct:
+		return t.Name()
+	case reflect.Map:
+		return fmt.Sprintf("{ [key: string]: %s }", typeScriptType(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		// `encoding/json` encodes byte slices as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return typeScriptType(t.Elem()) + "[]"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
+
+// generateOutputJSONSchema generates a JSON Schema describing the output document, in version `version` of the output
+// format
+func generateOutputJSONSchema(layout string, version string, indent string) (string, error) {
+	defs := newOrderedMap()
+	for _, described := range outputStructs(layout, version) {
+		properties := newOrderedMap()
+		required := make([]string, 0)
+
+		for _, field := range described.Fields {
+			if len(field.Const) > 0 {
+				properties.Set(field.Name, schemaOf("const", field.Const))
+			} else {
+				properties.Set(field.Name, outputFieldSchema(field.Type))
+			}
+			if !field.Optional {
+				required = append(required, field.Name)
+			}
+		}
+
+		defs.Set(described.Name, schemaOf("type", "object", "properties", properties, "required", required))
+	}
+
+	schema := schemaOf(
+		"$schema", jsonSchemaDialect,
+		"title", "protoc-gen-json output",
+		"description", fmt.Sprintf("schema_version %s of the output format", version),
+		"$ref", "#/$defs/Context",
+		"$defs", defs,
+	)
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(schema); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// outputFieldSchema returns the JSON Schema of the JSON encoding of `t`
+func outputFieldSchema(t reflect.Type) *orderedMap {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return outputFieldSchema(t.Elem())
+	case reflect.Struct:
+		return schemaOf("$ref", "#/$defs/"+t.Name())
+	case reflect.Map:
+		return schemaOf("type", "object", "additionalProperties", outputFieldSchema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schemaOf("type", "string", "contentEncoding", "base64")
+		}
+		return schemaOf("type", "array", "items", outputFieldSchema(t.Elem()))
+	case reflect.String:
+		return schemaOf("type", "string")
+	case reflect.Bool:
+		return schemaOf("type", "boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schemaOf("type", "integer")
+	case reflect.Float32, reflect.Float64:
+		return schemaOf("type", "number")
+	default:
+		return schemaOf()
+	}
+}
+
+// generateOutputDescriptions generates the requested descriptions of the output document's own format
+func generateOutputDescriptions(params *Options) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	ret := make([]*plugin_go.CodeGeneratorResponse_File, 0, 2)
+
+	if len(params.TypeScriptOut) > 0 {
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(params.TypeScriptOut),
+			Content: proto.String(generateOutputTypeScript(params.Collections, params.SchemaVersion)),
+		})
+	}
+
+	if len(params.OutputSchemaOut) > 0 {
+		content, err := generateOutputJSONSchema(params.Collections, params.SchemaVersion, params.Indent)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(params.OutputSchemaOut),
+			Content: proto.String(content),
+		})
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,82 @@
+package protojson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// outputSchemaDefs are the definitions which describe each collection in the JSON Schema of the output
+var outputSchemaDefs = map[string]string{
+	"files":       "File",
+	"services":    "Service",
+	"methods":     "Method",
+	"messages":    "Message",
+	"fields":      "Field",
+	"enums":       "Enum",
+	"enum_values": "EnumValue",
+}
+
+func TestOutputSchemaMatchesOutput(t *testing.T) {
+	for _, layout := range []string{"sorted", "array"} {
+		for _, version := range []string{"1.0", "1.2", OutputSchemaVersion} {
+			t.Run(layout+" "+version, func(t *testing.T) {
+				req := fixtureRequest(t, "typescript=output.d.ts,output_schema=output.schema.json,examples=true,collections="+layout+",schema_version="+version)
+				files := generateFixture(t, req)
+
+				var output, schema map[string]interface{}
+				if err := json.Unmarshal([]byte(files["output.json"]), &output); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal([]byte(files["output.schema.json"]), &schema); err != nil {
+					t.Fatal(err)
+				}
+
+				// Every object has every required key of its definition, and no keys which aren't described
+				check := func(def string, object interface{}) {
+					properties, _ := jsonPath(schema, "$defs", def, "properties").(map[string]interface{})
+					required, _ := jsonPath(schema, "$defs", def, "required").([]interface{})
+					keys := object.(map[string]interface{})
+					for key := range keys {
+						if _, found := properties[key]; !found {
+							t.Errorf("%s %q isn't described", def, key)
+						}
+					}
+					for _, key := range required {
+						if _, found := keys[key.(string)]; !found {
+							t.Errorf("%s %q is required, but not in the output", def, key)
+						}
+					}
+				}
+				check("Context", output)
+				for collection, def := range outputSchemaDefs {
+					objects := versionTestObjects(output[collection])
+					if len(objects) == 0 {
+						t.Fatalf("no %s in the output", collection)
+					}
+					for _, object := range objects {
+						check(def, object)
+					}
+				}
+
+				if meta, found := output["meta"]; found {
+					check("Meta", meta)
+					if got := jsonPath(schema, "$defs", "Meta", "properties", "schema_version", "const"); got != version {
+						t.Errorf("got schema_version %v in the schema", got)
+					}
+				}
+
+				declarations := files["output.d.ts"]
+				if !strings.Contains(declarations, "Describes schema_version "+version+" ") {
+					t.Errorf("the declarations don't describe their version")
+				}
+				if described := strings.Contains(declarations, "  scc: string;"); described != (version == OutputSchemaVersion) {
+					t.Errorf("got scc described %v in the declarations", described)
+				}
+				if described := strings.Contains(declarations, "interface Meta"); described != (version != "1.0") {
+					t.Errorf("got Meta described %v in the declarations", described)
+				}
+			})
+		}
+	}
+}
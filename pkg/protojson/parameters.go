@@ -0,0 +1,251 @@
+package protojson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options are the options passed to the plugin via `--json_opt`, which also configure `Build`.
+//
+// The parameter string is a comma-separated list of `key=value` pairs. Keys which accept multiple values may be
+// repeated. For backwards compatibility, a bare value without a key is treated as the output filename.
+type Options struct {
+	// Output is the name of the generated file
+	Output string
+	// Roots are the FQNs of services the output is restricted to (see `pruneToServices`)
+	Roots []string
+	// SchemaVersion is the version of the output format to emit, for consumers which don't support the current one
+	SchemaVersion string
+	// Collections is the layout of the collections in the output (see `orderedContext`)
+	Collections string
+	// Format is the name of the output format (see `newEncoder`)
+	Format string
+	// Indent is the indentation used for pretty-printed output; if empty, output is compact
+	Indent string
+	// JSONSchema enables JSON Schema output (see `generateJSONSchemas`)
+	JSONSchema string
+	// JSONSchemaOut is the filename (or directory, when emitting one schema per message) of the JSON Schema output
+	JSONSchemaOut string
+	// OpenAPI is the filename of the OpenAPI output; if empty, no OpenAPI document is generated (see `generateOpenAPI`)
+	OpenAPI string
+	// OpenAPITitle is the title of the OpenAPI document; it defaults to the packages of the generated files
+	OpenAPITitle string
+	// OpenAPIVersion is the version of the API described by the OpenAPI document
+	OpenAPIVersion string
+	// MarkdownPages is the grouping of pages in the Markdown output (see `markdownRenderer`)
+	MarkdownPages string
+	// Template is the path of a user-supplied template to render instead of a built-in format (see `templateRenderer`)
+	Template string
+	// TemplatePartials is a directory of additional templates, which are available to `Template`
+	TemplatePartials string
+	// TypeScriptOut is the filename of TypeScript declarations describing the output (see `generateOutputTypeScript`)
+	TypeScriptOut string
+	// OutputSchemaOut is the filename of a JSON Schema describing the output (see `generateOutputJSONSchema`)
+	OutputSchemaOut string
+	// Baseline is the path of a previous `json` output to check for breaking changes against (see `checkBreakingChanges`)
+	Baseline string
+	// BreakingOut is the filename of the breaking change report
+	BreakingOut string
+	// BreakingFail fails the compilation if there are any breaking changes
+	BreakingFail bool
+	// Lint are the lint rules to run, or LintAll (see `lintContext`)
+	Lint []string
+	// LintExcept are lint rules which aren't run, even if they are enabled by LintAll
+	LintExcept []string
+	// LintOut is the filename of the lint report
+	LintOut string
+	// LintFail fails the compilation if there are any lint findings
+	LintFail bool
+	// Externals adds stub index entries for the types referenced from non-generated files (see `addExternalTypes`)
+	Externals bool
+	// Examples enables example instances of every message (see `generateExamples`)
+	Examples bool
+	// ExampleOption is the FQN of a custom option which overrides the examples of fields and messages
+	ExampleOption string
+	// GraphPackage restricts the `dot` and `mermaid` formats to a package
+	GraphPackage string
+	// GraphService restricts the `dot` and `mermaid` formats to a service and the types reachable from it
+	GraphService string
+	// GraphCollapseNested merges nested types into their top-level message in the `dot` and `mermaid` formats
+	GraphCollapseNested bool
+	// CoverageOut is the filename of the documentation coverage report; if empty, coverage isn't measured
+	CoverageOut string
+	// CoverageMin is the documentation coverage percentage below which the compilation fails
+	CoverageMin float64
+
+	// Warn receives the diagnostics which don't stop generation, EG the objects dropped by `Roots` or the findings of
+	// checks which don't fail. It can't be set by a parameter; if nil, diagnostics are discarded.
+	Warn func(message string)
+}
+
+// warn passes a diagnostic to `Warn`, if it is set
+func (o *Options) warn(format string, args ...interface{}) {
+	if o.Warn != nil {
+		o.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// DefaultOptions returns the options used when none are passed
+func DefaultOptions() *Options {
+	return &Options{
+		Output:      "output.json",
+		Collections: CollectionsSorted,
+		Format:      "json",
+		Indent:      "  ",
+
+		SchemaVersion:  OutputSchemaVersion,
+		OpenAPIVersion: "0.0.0",
+		MarkdownPages:  MarkdownPagesFile,
+		BreakingOut:    "breaking_changes.json",
+		LintOut:        "lint.json",
+	}
+}
+
+// ParseOptions parses the raw parameter string passed by `protoc`
+func ParseOptions(raw string) (*Options, error) {
+	params := DefaultOptions()
+
+	for _, opt := range strings.Split(raw, ",") {
+		opt = strings.TrimSpace(opt)
+		if len(opt) == 0 {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(opt, "=")
+
+		// A bare value is the output filename
+		if !hasValue {
+			params.Output = key
+			continue
+		}
+
+		switch key {
+		case "out":
+			params.Output = value
+		case "root":
+			params.Roots = append(params.Roots, GetFQN(value))
+		case "collections":
+			switch value {
+			case CollectionsSorted, CollectionsDeclared, CollectionsArray:
+				params.Collections = value
+			default:
+				return nil, fmt.Errorf("unknown collections layout %q", value)
+			}
+		case "schema_version":
+			if !validSchemaVersion(value) {
+				return nil, fmt.Errorf("unknown output schema version %q (supported: %s)", value, supportedSchemaVersions())
+			}
+			params.SchemaVersion = value
+		case "format":
+			params.Format = value
+		case "indent":
+			if value == "tab" {
+				params.Indent = "\t"
+				continue
+			}
+
+			width, err := strconv.Atoi(value)
+			if err != nil || width < 0 {
+				return nil, fmt.Errorf("indent must be a non-negative number of spaces or \"tab\", got %q", value)
+			}
+			params.Indent = strings.Repeat(" ", width)
+		case "json_schema":
+			switch value {
+			case JSONSchemaBundle, JSONSchemaMessages:
+				params.JSONSchema = value
+			default:
+				return nil, fmt.Errorf("unknown JSON Schema mode %q", value)
+			}
+		case "json_schema_out":
+			params.JSONSchemaOut = value
+		case "openapi":
+			params.OpenAPI = value
+		case "openapi_title":
+			params.OpenAPITitle = value
+		case "openapi_version":
+			params.OpenAPIVersion = value
+		case "markdown_pages":
+			params.MarkdownPages = value
+		case "template":
+			params.Template = value
+		case "template_partials":
+			params.TemplatePartials = value
+		case "typescript":
+			params.TypeScriptOut = value
+		case "output_schema":
+			params.OutputSchemaOut = value
+		case "baseline":
+			params.Baseline = value
+		case "breaking_out":
+			params.BreakingOut = value
+		case "breaking_fail":
+			fail, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("breaking_fail must be true or false, got %q", value)
+			}
+			params.BreakingFail = fail
+		case "lint", "lint_except":
+			if !validLintRule(value) {
+				return nil, fmt.Errorf("unknown lint rule %q", value)
+			}
+			if key == "lint_except" && value == LintAll {
+				return nil, fmt.Errorf("lint_except must name a lint rule; to run none, leave out lint")
+			}
+			if key == "lint" {
+				params.Lint = append(params.Lint, value)
+			} else {
+				params.LintExcept = append(params.LintExcept, value)
+			}
+		case "lint_out":
+			params.LintOut = value
+		case "lint_fail":
+			fail, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("lint_fail must be true or false, got %q", value)
+			}
+			params.LintFail = fail
+		case "externals":
+			externals, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("externals must be true or false, got %q", value)
+			}
+			params.Externals = externals
+		case "examples":
+			examples, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("examples must be true or false, got %q", value)
+			}
+			params.Examples = examples
+		case "example_option":
+			params.ExampleOption = GetFQN(value)
+		case "graph_package":
+			params.GraphPackage = value
+		case "graph_service":
+			params.GraphService = GetFQN(value)
+		case "graph_collapse_nested":
+			collapse, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("graph_collapse_nested must be true or false, got %q", value)
+			}
+			params.GraphCollapseNested = collapse
+		case "coverage":
+			params.CoverageOut = value
+		case "coverage_min":
+			min, err := strconv.ParseFloat(value, 64)
+			if err != nil || min < 0 || min > 100 {
+				return nil, fmt.Errorf("coverage_min must be a percentage between 0 and 100, got %q", value)
+			}
+			params.CoverageMin = min
+		default:
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+
+	// Older formats can't mark index entries as external, so their consumers would look for the stubs in collections
+	if params.Externals && schemaVersionBefore(params.SchemaVersion, "1.6") {
+		return nil, fmt.Errorf("externals requires schema_version 1.6 or later, got %q", params.SchemaVersion)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,55 @@
+package protojson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("api.json, lint=all,lint_except=UNUSED_MESSAGE,externals=true,schema_version=1.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Output != "api.json" {
+		t.Errorf("Output is %q, want api.json", opts.Output)
+	}
+	if !reflect.DeepEqual(opts.Lint, []string{LintAll}) || !reflect.DeepEqual(opts.LintExcept, []string{"UNUSED_MESSAGE"}) {
+		t.Errorf("Lint is %q except %q, want all except UNUSED_MESSAGE", opts.Lint, opts.LintExcept)
+	}
+	if !opts.Externals {
+		t.Errorf("Externals is false, want true")
+	}
+
+	// Externals and examples both change the output, so they're off unless asked for
+	defaults, err := ParseOptions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaults.Externals || defaults.Examples {
+		t.Errorf("Externals is %v and Examples is %v by default, want false", defaults.Externals, defaults.Examples)
+	}
+	opts, err = ParseOptions("examples=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.Examples {
+		t.Errorf("Examples is false, want true")
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	tests := []string{
+		"nope=1",
+		"lint=NOPE",
+		"lint_except=all",
+		"externals=maybe",
+		"externals=true,schema_version=1.5",
+		"schema_version=0.9",
+		"collections=nope",
+	}
+	for _, parameter := range tests {
+		if _, err := ParseOptions(parameter); err == nil {
+			t.Errorf("%s was accepted", parameter)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Package protojson builds a JSON-friendly model of protobuf files, and renders it in the formats of the
+// `protoc-gen-json` plugin. `Build` parses a `protoc` request into a `Context`; `Generate` runs the whole plugin.
+package protojson
+
+import (
+	"fmt"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pseudomuto/protokit"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Build parses the files to generate in a `protoc` request into a context, with every option which affects the model
+// applied: `Roots`, `Externals`, `Examples` and `ExampleOption`. Start from `DefaultOptions` to match the plugin.
+// Errors are problems with the request or the options.
+func Build(req *pluginpb.CodeGeneratorRequest, opts Options) (*Context, error) {
+	// Prepare context
+	context := NewContext()
+	context.Meta = NewMeta(req)
+
+	// Parse request via protokit
+	descriptors := protokit.ParseCodeGenRequest(req)
+
+	// First parse out all custom options defined in all files
+	context.CustomOptions = parseAllCustomOptionDefinitions(descriptors)
+
+	// Then, parse everything defined in each file, EXCEPT for the custom options defined on resources
+	for i, d := range descriptors {
+		parseFile(d, context, i)
+	}
+
+	// Finally, parse all the custom options that were set on fields/etc.
+	// We have to do this step last because a custom option might be of a type that isn't defined until everything
+	// has been parsed
+	parseAllCustomOptionValues(context, opts.warn)
+
+	// If requested, restrict the output to what is reachable from a set of services
+	if len(opts.Roots) > 0 {
+		dropped, err := pruneToServices(context, opts.Roots)
+		if err != nil {
+			return nil, err
+		}
+
+		lines := append([]string{fmt.Sprintf("dropped %d objects not reachable from %s", len(dropped), strings.Join(opts.Roots, ", "))}, dropped...)
+		opts.warn("%s", strings.Join(lines, "\n  "))
+	}
+
+	// Mark recursive messages, once the message graph is final
+	markCycles(context)
+
+	markWellKnownTypes(context)
+
+	// If requested, index stubs of the types referenced from files which aren't generated
+	if opts.Externals {
+		addExternalTypes(context, req.ProtoFile)
+	}
+
+	if opts.Examples {
+		generateExamples(context, opts.ExampleOption)
+	}
+
+	return context, nil
+}
+
+// Generate runs the plugin: it builds the context of a `protoc` request with the options in its parameter, runs the
+// requested checks, and renders the requested outputs. Diagnostics are passed to `warn` (see `Options.Warn`), which
+// may be nil.
+func Generate(req *plugin_go.CodeGeneratorRequest, warn func(message string)) (*plugin_go.CodeGeneratorResponse, error) {
+	// Parse plugin parameters
+	params, err := ParseOptions(req.GetParameter())
+	if err != nil {
+		return errorResponse(err), nil
+	}
+	params.Warn = warn
+
+	context, err := Build(req, *params)
+	if err != nil {
+		return errorResponse(err), nil
+	}
+
+	// If requested, check for breaking changes against a previous output
+	var breaking *BreakingReport
+	if len(params.Baseline) > 0 {
+		breaking, err = checkBreakingChanges(context, params.Baseline)
+		if err != nil {
+			return errorResponse(err), nil
+		}
+
+		if len(breaking.BreakingChanges) > 0 {
+			if params.BreakingFail {
+				return errorResponse(breaking.Error()), nil
+			}
+			params.warn("%v", breaking.Error())
+		}
+	}
+
+	// If requested, run lint rules over the whole model
+	var lint *LintReport
+	if len(params.Lint) > 0 {
+		lint = lintContext(context, params.Lint, params.LintExcept)
+
+		if len(lint.Findings) > 0 {
+			if params.LintFail {
+				return errorResponse(fmt.Errorf("%d lint findings:\n%s", len(lint.Findings), strings.TrimSuffix(lint.String(), "\n"))), nil
+			}
+			params.warn("%s", strings.TrimSuffix(lint.String(), "\n"))
+		}
+	}
+
+	// If requested, measure documentation coverage
+	var coverage *CoverageReport
+	if len(params.CoverageOut) > 0 || params.CoverageMin > 0 {
+		coverage = measureCoverage(context)
+		params.warn("%s", strings.TrimSuffix(coverage.String(), "\n"))
+
+		if err := coverage.belowThreshold(params.CoverageMin); err != nil {
+			return errorResponse(err), nil
+		}
+	}
+
+	// Render the requested output format
+	renderer, err := NewRenderer(params)
+	if err != nil {
+		return errorResponse(err), nil
+	}
+
+	// The objects which scope diagrams can only be checked once the model is built
+	if params.Format == "dot" || params.Format == "mermaid" {
+		if err := newGraphOptions(params).validate(context); err != nil {
+			return errorResponse(err), nil
+		}
+	}
+
+	files, err := renderer.Render(context)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tell protoc we're done
+	ret := new(plugin_go.CodeGeneratorResponse)
+	ret.File = append(ret.File, files...)
+
+	// Add any additional outputs
+	if len(params.JSONSchema) > 0 {
+		schemas, err := generateJSONSchemas(context, params)
+		if err != nil {
+			return nil, err
+		}
+		ret.File = append(ret.File, schemas...)
+	}
+	if len(params.OpenAPI) > 0 {
+		document, err := generateOpenAPI(context, params)
+		if err != nil {
+			return nil, err
+		}
+		ret.File = append(ret.File, document)
+	}
+
+	if breaking != nil {
+		report, err := breaking.File(params.BreakingOut, params.Indent)
+		if err != nil {
+			return nil, err
+		}
+		ret.File = append(ret.File, report)
+	}
+
+	if lint != nil {
+		report, err := lint.File(params.LintOut, params.Indent)
+		if err != nil {
+			return nil, err
+		}
+		ret.File = append(ret.File, report)
+	}
+
+	if coverage != nil && len(params.CoverageOut) > 0 {
+		report, err := coverage.File(params.CoverageOut, params.Indent)
+		if err != nil {
+			return nil, err
+		}
+		ret.File = append(ret.File, report)
+	}
+
+	descriptions, err := generateOutputDescriptions(params)
+	if err != nil {
+		return nil, err
+	}
+	ret.File = append(ret.File, descriptions...)
+
+	// Tell `protoc` that we support optional proto3 fields
+	// We need to heap-allocate this so we can do pointer stuff because the response object
+	// has a pointer to a uint64 which might be nil (why??)
+	ret.SupportedFeatures = new(uint64)
+	*ret.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+	return ret, nil
+}
+
+// errorResponse builds a response which reports `err` to `protoc`.
+// This should be used for problems with the input (rather than internal failures) so `protoc` can report them nicely.
+func errorResponse(err error) *plugin_go.CodeGeneratorResponse {
+	ret := new(plugin_go.CodeGeneratorResponse)
+	ret.Error = proto.String(err.Error())
+	return ret
+}
+
+// parseFile parses a protobuf file and all its constituent parts.
+// `declIndex` is the position of the file in the list of files to generate.
+func parseFile(fileProto *protokit.FileDescriptor, context *Context, declIndex int) {
+	file := &File{
+		Descriptor:       fileProto,
+		DeclarationIndex: declIndex,
+
+		Name:        fileProto.GetName(),
+		Package:     fileProto.GetPackage(),
+		Description: fileProto.GetPackageComments().String(),
+
+		Services:   make([]string, 0),
+		Methods:    make([]string, 0),
+		Messages:   make([]string, 0),
+		Fields:     make([]string, 0),
+		Enums:      make([]string, 0),
+		EnumValues: make([]string, 0),
+	}
+
+	// Handle all services in fileProto
+	for i, service := range fileProto.GetServices() {
+		parseService(service, context, file, i)
+	}
+
+	// Handle all messages in fileProto
+	for i, msg := range fileProto.GetMessages() {
+		parseMessage(msg, context, file, nil, i)
+	}
+
+	// Handle all enums in fileProto
+	for i, enum := range fileProto.GetEnums() {
+		parseEnum(enum, context, file, nil, i)
+	}
+
+	// Store file in context
+	context.StoreFile(file)
+
+	// TODO: Handle extensions defined in a fileProto?
+}
+
+// parseMessage parses a protobuf message and its fields.
+// `declIndex` is the position of the message within its parent (either `declMessage` or, if nil, `declFile`).
+func parseMessage(messageProto *protokit.Descriptor, context *Context, declFile *File, declMessage *Message, declIndex int) {
+	message := &Message{
+		Descriptor:       messageProto,
+		DeclarationIndex: declIndex,
+
+		Name:        messageProto.GetName(),
+		FullName:    GetFQN(messageProto.GetFullName()),
+		Description: messageProto.GetComments().String(),
+		IsMapEntry:  messageProto.Options.GetMapEntry(),
+
+		Fields:   make([]string, 0),
+		Messages: make([]string, 0),
+		Enums:    make([]string, 0),
+
+		ReservedNames: messageProto.GetReservedName(),
+	}
+
+	// Message reserved ranges exclude their end, unlike enum reserved ranges
+	for _, reserved := range messageProto.GetReservedRange() {
+		message.ReservedRanges = append(message.ReservedRanges, &ReservedRange{Start: reserved.GetStart(), End: reserved.GetEnd() - 1})
+	}
+
+	//Put messageProto into declFile.Messages and, if non-null, declMessage.Messages
+	declFile.Messages = append(declFile.Messages, message.FullName)
+	if declMessage != nil {
+		declMessage.Messages = append(declMessage.Messages, message.FullName)
+	}
+
+	// Handle all fields in messageProto
+	for i, fd := range messageProto.GetMessageFields() {
+		parseField(fd, context, declFile, message, i)
+	}
+
+	// Handle all sub-messages in messageProto
+	for i, sm := range messageProto.GetMessages() {
+		parseMessage(sm, context, declFile, message, i)
+	}
+
+	// Handle all sub-enums in messageProto
+	for i, enum := range messageProto.GetEnums() {
+		parseEnum(enum, context, declFile, message, i)
+	}
+
+	//TODO: Handle extensions defined in a messageProto?
+
+	//Store message in context
+	context.StoreMessage(message, messageProto)
+}
+
+// parseField parses a field in a protobuf message, and its options.
+// `declIndex` is the position of the field within `declMessage`.
+func parseField(fieldProto *protokit.FieldDescriptor, context *Context, declFile *File, declMessage *Message, declIndex int) {
+	// Figure out type names
+	typeName := fieldProto.GetTypeName()
+	fullTypeName := fieldProto.GetType().String()
+
+	// If typeName is length 0, it's a proto primitive
+	if len(typeName) == 0 {
+		typeName = strings.Replace(fieldProto.GetType().String(), "TYPE_", "", -1)
+		typeName = strings.ToLower(typeName)
+		fullTypeName = typeName
+	} else {
+		// Otherwise, `typeName` is the fully-qualified type,
+		// and `fullTypeName` will be `TYPE_MESSAGE`.
+
+		fullTypeName = typeName
+		split := strings.Split(typeName, ".")
+		typeName = split[len(split)-1]
+	}
+
+	// Determine FQN
+	fqn := GetFQN(fieldProto.GetFullName())
+
+	// Find the oneof this field is part of, ignoring the synthetic oneofs generated for proto3 `optional` fields
+	oneof := ""
+	if fieldProto.OneofIndex != nil && !fieldProto.GetProto3Optional() {
+		oneof = declMessage.Descriptor.GetOneofDecl()[fieldProto.GetOneofIndex()].GetName()
+	}
+
+	field := &Field{
+		Descriptor:       fieldProto,
+		DeclarationIndex: declIndex,
+
+		Name:        fieldProto.GetName(),
+		FullName:    fqn,
+		JSONName:    fieldProto.GetJsonName(),
+		Number:      fieldProto.GetNumber(),
+		Label:       fieldProto.GetLabel().String(),
+		Type:        GetFQN(typeName),
+		FullType:    GetFQN(fullTypeName),
+		Oneof:       oneof,
+		Description: fieldProto.GetComments().String(),
+	}
+
+	// Store fieldProto in declFile and declMessage
+	declFile.Fields = append(declFile.Fields, fqn)
+	declMessage.Fields = append(declMessage.Fields, fqn)
+
+	//Store field in context
+	context.StoreField(field, fieldProto)
+}
+
+// parseEnum parses an enum and its values.
+// `declIndex` is the position of the enum within its parent (either `declMessage` or, if nil, `declFile`).
+func parseEnum(enumProto *protokit.EnumDescriptor, context *Context, declFile *File, declMessage *Message, declIndex int) {
+	enum := &Enum{
+		Descriptor:       enumProto,
+		DeclarationIndex: declIndex,
+
+		Name:        enumProto.GetName(),
+		FullName:    GetFQN(enumProto.GetFullName()),
+		Description: enumProto.GetComments().String(),
+		Values:      make([]string, 0),
+
+		ReservedNames: enumProto.GetReservedName(),
+	}
+
+	for _, reserved := range enumProto.GetReservedRange() {
+		enum.ReservedRanges = append(enum.ReservedRanges, &ReservedRange{Start: reserved.GetStart(), End: reserved.GetEnd()})
+	}
+
+	//Store enum in declFile.Enums and, if non-null, declMessage.Enums
+	declFile.Enums = append(declFile.Enums, enum.FullName)
+	if declMessage != nil {
+		declMessage.Enums = append(declMessage.Enums, enum.FullName)
+	}
+
+	for i, val := range enumProto.GetValues() {
+		parseEnumValue(val, context, declFile, enum, i)
+	}
+
+	// Index enumProto
+	context.StoreEnum(enum, enumProto)
+}
+
+// parseEnumValue parses a value of an enum.
+// `declIndex` is the position of the value within `declEnum`.
+func parseEnumValue(enumValProto *protokit.EnumValueDescriptor, context *Context, declFile *File, declEnum *Enum, declIndex int) {
+	enumVal := &EnumValue{
+		Descriptor:       enumValProto,
+		DeclarationIndex: declIndex,
+
+		Name:        enumValProto.GetName(),
+		FullName:    GetFQN(enumValProto.GetFullName()),
+		Description: enumValProto.GetComments().String(),
+		Value:       enumValProto.GetNumber(),
+	}
+
+	//Store enumVal in declFile.EnumValues and declEenum.Values
+	declFile.EnumValues = append(declFile.EnumValues, enumVal.FullName)
+	declEnum.Values = append(declEnum.Values, enumVal.FullName)
+
+	//Store enumVal in context
+	context.StoreEnumValue(enumVal, enumValProto)
+}
+
+// parseService parses a service in a protobuf file, and its methods.
+// `declIndex` is the position of the service within `declFile`.
+func parseService(serviceProto *protokit.ServiceDescriptor, context *Context, declFile *File, declIndex int) {
+	service := &Service{
+		Descriptor:       serviceProto,
+		DeclarationIndex: declIndex,
+
+		Name:        serviceProto.GetName(),
+		FullName:    GetFQN(serviceProto.GetFullName()),
+		Description: serviceProto.GetComments().String(),
+		Methods:     make([]string, 0),
+	}
+
+	// Store service in declFile.Services
+	declFile.Services = append(declFile.Services, service.FullName)
+
+	for i, md := range serviceProto.GetMethods() {
+		parseMethod(md, context, declFile, service, i)
+	}
+
+	//Store service in context
+	context.StoreService(service, serviceProto)
+}
+
+// parseMethod parses a method in a service.
+// `declIndex` is the position of the method within `declService`.
+func parseMethod(methodProto *protokit.MethodDescriptor, context *Context, declFile *File, declService *Service, declIndex int) {
+	method := &Method{
+		Descriptor:       methodProto,
+		DeclarationIndex: declIndex,
+
+		Name:        methodProto.GetName(),
+		FullName:    GetFQN(methodProto.GetFullName()),
+		InputType:   GetFQN(methodProto.GetInputType()),
+		OutputType:  GetFQN(methodProto.GetOutputType()),
+		Description: methodProto.GetComments().String(),
+		HTTPRules:   parseHTTPRules(methodProto.GetOptions()),
+
+		ClientStreaming: methodProto.GetClientStreaming(),
+		ServerStreaming: methodProto.GetServerStreaming(),
+	}
+
+	//Store method in declFile.Methods and declService.Methods
+	declFile.Methods = append(declFile.Methods, method.FullName)
+	declService.Methods = append(declService.Methods, method.FullName)
+
+	// Store index in context
+	context.StoreMethod(method, methodProto)
+}
@@ -0,0 +1,149 @@
+package protojson
+
+import (
+	"fmt"
+	"sort"
+)
+
+// pruneToServices restricts `context` to the services in `roots`, their methods, and every message and enum
+// transitively reachable from those methods' input and output types (see `reachableFrom`).
+// Everything else is removed from the collections and the index; the FQNs of the removed objects are returned.
+func pruneToServices(context *Context, roots []string) ([]string, error) {
+	reachable, err := reachableFrom(context, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove everything which wasn't reached
+	dropped := make([]string, 0)
+	for fqn := range context.Index {
+		if !reachable[fqn] {
+			dropped = append(dropped, fqn)
+			delete(context.Index, fqn)
+		}
+	}
+	sort.Strings(dropped)
+
+	for fqn := range context.Services {
+		if !reachable[fqn] {
+			delete(context.Services, fqn)
+		}
+	}
+	for fqn := range context.Methods {
+		if !reachable[fqn] {
+			delete(context.Methods, fqn)
+		}
+	}
+	for fqn, message := range context.Messages {
+		if !reachable[fqn] {
+			delete(context.Messages, fqn)
+			continue
+		}
+		message.Messages = filterReachable(message.Messages, reachable)
+		message.Enums = filterReachable(message.Enums, reachable)
+	}
+	for fqn := range context.Fields {
+		if !reachable[fqn] {
+			delete(context.Fields, fqn)
+		}
+	}
+	for fqn := range context.Enums {
+		if !reachable[fqn] {
+			delete(context.Enums, fqn)
+		}
+	}
+	for fqn := range context.EnumValues {
+		if !reachable[fqn] {
+			delete(context.EnumValues, fqn)
+		}
+	}
+
+	// Files aren't indexed, so they're kept as long as they still declare something
+	for name, file := range context.Files {
+		file.Services = filterReachable(file.Services, reachable)
+		file.Methods = filterReachable(file.Methods, reachable)
+		file.Messages = filterReachable(file.Messages, reachable)
+		file.Fields = filterReachable(file.Fields, reachable)
+		file.Enums = filterReachable(file.Enums, reachable)
+		file.EnumValues = filterReachable(file.EnumValues, reachable)
+
+		if len(file.Services)+len(file.Messages)+len(file.Enums) == 0 {
+			delete(context.Files, name)
+		}
+	}
+
+	return dropped, nil
+}
+
+// reachableFrom returns the FQNs of the services in `roots`, their methods, and every message, field, enum and enum
+// value transitively reachable from those methods' input and output types.
+// Nested types can't be declared without the messages they're nested in, so those are reachable too, along with
+// everything reachable from them.
+func reachableFrom(context *Context, roots []string) (map[string]bool, error) {
+	for _, root := range roots {
+		if _, found := context.Services[root]; !found {
+			return nil, fmt.Errorf("root %q is not a service defined in the generated files", root)
+		}
+	}
+
+	roots = append([]string{}, roots...)
+	for {
+		reachable := reachableObjects(context, roots)
+
+		ancestors := make([]string, 0)
+		for fqn := range reachable {
+			if entry, found := context.Index[fqn]; found && len(entry.Parent) > 0 && !reachable[entry.Parent] {
+				ancestors = append(ancestors, entry.Parent)
+			}
+		}
+		if len(ancestors) == 0 {
+			return reachable, nil
+		}
+		roots = append(roots, ancestors...)
+	}
+}
+
+// reachableObjects returns the FQNs of the objects in `roots`, and every object transitively reachable from them
+func reachableObjects(context *Context, roots []string) map[string]bool {
+	reachable := make(map[string]bool)
+	queue := append(make([]string, 0, len(roots)), roots...)
+
+	// Walk the reference graph breadth-first
+	for len(queue) > 0 {
+		fqn := queue[0]
+		queue = queue[1:]
+
+		if reachable[fqn] {
+			continue
+		}
+		reachable[fqn] = true
+
+		if service, found := context.Services[fqn]; found {
+			queue = append(queue, service.Methods...)
+		} else if method, found := context.Methods[fqn]; found {
+			queue = append(queue, method.InputType, method.OutputType)
+		} else if message, found := context.Messages[fqn]; found {
+			queue = append(queue, message.Fields...)
+		} else if field, found := context.Fields[fqn]; found {
+			// Primitive types won't be found in the index, so only follow references to messages and enums
+			if _, indexed := context.Index[field.FullType]; indexed {
+				queue = append(queue, field.FullType)
+			}
+		} else if enum, found := context.Enums[fqn]; found {
+			queue = append(queue, enum.Values...)
+		}
+	}
+
+	return reachable
+}
+
+// filterReachable returns the FQNs in `fqns` which are present in `reachable`, preserving their order
+func filterReachable(fqns []string, reachable map[string]bool) []string {
+	ret := make([]string, 0, len(fqns))
+	for _, fqn := range fqns {
+		if reachable[fqn] {
+			ret = append(ret, fqn)
+		}
+	}
+	return ret
+}
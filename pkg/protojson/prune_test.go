@@ -0,0 +1,72 @@
+package protojson
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPruneToServices(t *testing.T) {
+	req := fixtureRequest(t, "root=com.pseudomuto.protokit.v1.BookingService")
+	// Return a type nested in a message which the service doesn't otherwise refer to
+	method := fixtureFile(t, req, "booking.proto").GetService()[0].GetMethod()[0]
+	method.OutputType = proto.String(".com.pseudomuto.protokit.v1.CreateListResponse.Status")
+
+	ctx := buildFixture(t, req)
+
+	tests := []struct {
+		fqn  string
+		kept bool
+	}{
+		{"com.pseudomuto.protokit.v1.BookingService", true},
+		{"com.pseudomuto.protokit.v1.BookingService.BookVehicle", true},
+		{"com.pseudomuto.protokit.v1.Booking", true},
+		{"com.pseudomuto.protokit.v1.BookingStatus.StatusCode", true},
+		{"com.pseudomuto.protokit.v1.CreateListResponse.Status", true},
+		// The message the response is nested in, and what it refers to
+		{"com.pseudomuto.protokit.v1.CreateListResponse", true},
+		{"com.pseudomuto.protokit.v1.List", true},
+		{"com.pseudomuto.protokit.v1.ListType", true},
+		{"com.pseudomuto.protokit.v1.Todo", false},
+		{"com.pseudomuto.protokit.v1.AddItemRequest", false},
+		{"com.pseudomuto.protokit.v1.BookingType", false},
+	}
+	for _, test := range tests {
+		if _, found := ctx.Lookup(test.fqn); found != test.kept {
+			t.Errorf("%s: kept = %v, want %v", test.fqn, found, test.kept)
+		}
+	}
+
+	for fqn, entry := range ctx.Index {
+		if _, found := ctx.Index[entry.Parent]; len(entry.Parent) > 0 && !found {
+			t.Errorf("the parent %s of %s was dropped", entry.Parent, fqn)
+		}
+	}
+}
+
+func TestPruneToServicesUnknownRoot(t *testing.T) {
+	opts, err := ParseOptions("root=com.pseudomuto.protokit.v1.Booking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(fixtureRequest(t, ""), *opts); err == nil {
+		t.Errorf("a message was accepted as a root")
+	}
+}
+
+func TestPruneToServicesWarnsOfDroppedObjects(t *testing.T) {
+	opts, err := ParseOptions("root=com.pseudomuto.protokit.v1.BookingService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := make([]string, 0)
+	opts.Warn = func(message string) { warnings = append(warnings, message) }
+
+	if _, err := Build(fixtureRequest(t, ""), *opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "\n  com.pseudomuto.protokit.v1.Todo\n") {
+		t.Errorf("got warnings %q, want the dropped objects", warnings)
+	}
+}
@@ -0,0 +1,139 @@
+package protojson
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field numbers of the declarations in `google.protobuf.FileDescriptorProto` and its children, which make up the
+// paths of `SourceCodeInfo` locations
+const (
+	fileMessagePath   = 4
+	fileEnumPath      = 5
+	fileServicePath   = 6
+	messageFieldPath  = 2
+	messageNestedPath = 3
+	messageEnumPath   = 4
+	enumValuePath     = 2
+	serviceMethodPath = 2
+)
+
+// SourceLocation is the position of a declaration in a proto file. Lines and columns start at 1.
+type SourceLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (l *SourceLocation) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// sourceLocator finds the source locations of objects, from the `SourceCodeInfo` of the files they're declared in
+type sourceLocator struct {
+	ctx *Context
+	// locations are the locations in each file, by their joined paths (see `sourcePathKey`)
+	locations map[string]map[string]*descriptorpb.SourceCodeInfo_Location
+}
+
+func newSourceLocator(ctx *Context) *sourceLocator {
+	return &sourceLocator{ctx: ctx, locations: make(map[string]map[string]*descriptorpb.SourceCodeInfo_Location)}
+}
+
+// Locate returns the location of the object `fqn`. If its position isn't known (EG, `protoc` was run without source
+// info, or the context was read back from an output), only the file is set.
+func (l *sourceLocator) Locate(fqn string) *SourceLocation {
+	entry, found := l.ctx.Index[fqn]
+	if !found {
+		if _, isFile := l.ctx.Files[fqn]; isFile {
+			return &SourceLocation{File: fqn}
+		}
+		return &SourceLocation{}
+	}
+
+	ret := &SourceLocation{File: entry.File}
+
+	path := l.ctx.sourcePath(fqn)
+	if path == nil {
+		return ret
+	}
+
+	if location, found := l.fileLocations(entry.File)[sourcePathKey(path)]; found && len(location.GetSpan()) >= 3 {
+		ret.Line = int(location.GetSpan()[0]) + 1
+		ret.Column = int(location.GetSpan()[1]) + 1
+	}
+	return ret
+}
+
+// fileLocations returns the locations in the file `name`, by path
+func (l *sourceLocator) fileLocations(name string) map[string]*descriptorpb.SourceCodeInfo_Location {
+	if locations, found := l.locations[name]; found {
+		return locations
+	}
+
+	locations := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	if file, found := l.ctx.Files[name]; found && file.Descriptor != nil {
+		for _, location := range file.Descriptor.GetSourceCodeInfo().GetLocation() {
+			locations[sourcePathKey(location.GetPath())] = location
+		}
+	}
+
+	l.locations[name] = locations
+	return locations
+}
+
+// sourcePath returns the path of the declaration of `fqn` within its file's descriptor, or nil if it isn't known
+func (ctx *Context) sourcePath(fqn string) []int32 {
+	entry, found := ctx.Index[fqn]
+	if !found {
+		return nil
+	}
+
+	// Paths are built from the parent's path, so copy it before appending to avoid sharing its backing array
+	within := func(parent string, path ...int32) []int32 {
+		parentPath := ctx.sourcePath(parent)
+		if parentPath == nil {
+			return nil
+		}
+		return append(append(make([]int32, 0, len(parentPath)+len(path)), parentPath...), path...)
+	}
+
+	switch entry.Type {
+	case "service":
+		return []int32{fileServicePath, int32(ctx.Services[fqn].DeclarationIndex)}
+	case "method":
+		// Methods aren't indexed with their parent, but their FQN is always within their service's
+		service := fqn[:strings.LastIndex(fqn, ".")]
+		return within(service, serviceMethodPath, int32(ctx.Methods[fqn].DeclarationIndex))
+	case "message":
+		if len(entry.Parent) == 0 {
+			return []int32{fileMessagePath, int32(ctx.Messages[fqn].DeclarationIndex)}
+		}
+		return within(entry.Parent, messageNestedPath, int32(ctx.Messages[fqn].DeclarationIndex))
+	case "enum":
+		if len(entry.Parent) == 0 {
+			return []int32{fileEnumPath, int32(ctx.Enums[fqn].DeclarationIndex)}
+		}
+		return within(entry.Parent, messageEnumPath, int32(ctx.Enums[fqn].DeclarationIndex))
+	case "field":
+		return within(entry.Parent, messageFieldPath, int32(ctx.Fields[fqn].DeclarationIndex))
+	case "enum_value":
+		return within(entry.Parent, enumValuePath, int32(ctx.EnumValues[fqn].DeclarationIndex))
+	default:
+		return nil
+	}
+}
+
+// sourcePathKey joins a source path into a map key
+func sourcePathKey(path []int32) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		parts[i] = fmt.Sprint(part)
+	}
+	return strings.Join(parts, ".")
+}
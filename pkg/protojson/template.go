@@ -0,0 +1,165 @@
+package protojson
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/proto"
+)
+
+// templateRenderer renders a context with a user-supplied Go `text/template`.
+//
+// The template is executed with the whole Context as its data. Its output is written to the output file, unless
+// it is blank; templates can also write any number of additional files with the `emit` function.
+type templateRenderer struct {
+	path     string
+	partials string
+	output   string
+}
+
+func newTemplateRenderer(params *Options) *templateRenderer {
+	return &templateRenderer{
+		path:     params.Template,
+		partials: params.TemplatePartials,
+		output:   params.Output,
+	}
+}
+
+func (r *templateRenderer) Render(ctx *Context) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	ret := make([]*plugin_go.CodeGeneratorResponse_File, 0)
+
+	// `emit` needs to refer to the parsed template, so it is bound after parsing
+	var tmpl *template.Template
+	funcs := templateFuncs(ctx)
+	funcs["emit"] = func(filename string, name string, data interface{}) (string, error) {
+		buf := new(bytes.Buffer)
+		if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+			return "", err
+		}
+
+		ret = append(ret, &plugin_go.CodeGeneratorResponse_File{
+			Name:    proto.String(filename),
+			Content: proto.String(buf.String()),
+		})
+		return "", nil
+	}
+
+	tmpl, err := template.New(filepath.Base(r.path)).Funcs(funcs).ParseFiles(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	if len(r.partials) > 0 {
+		partials, err := filepath.Glob(filepath.Join(r.partials, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		if len(partials) > 0 {
+			if tmpl, err = tmpl.ParseFiles(partials...); err != nil {
+				return nil, fmt.Errorf("failed to parse template partials: %w", err)
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, ctx); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if len(strings.TrimSpace(buf.String())) > 0 {
+		ret = append([]*plugin_go.CodeGeneratorResponse_File{{
+			Name:    proto.String(r.output),
+			Content: proto.String(buf.String()),
+		}}, ret...)
+	}
+
+	return ret, nil
+}
+
+// templateFuncs are the functions available to user-supplied templates.
+//
+// Functions which take an object also accept the object's FQN.
+func templateFuncs(ctx *Context) template.FuncMap {
+	// entity resolves FQNs to objects, and passes objects through untouched
+	entity := func(v interface{}) interface{} {
+		if fqn, isString := v.(string); isString {
+			resolved, _ := ctx.Lookup(fqn)
+			return resolved
+		}
+		return v
+	}
+
+	return template.FuncMap{
+		// Navigation
+		"resolve": func(fqn string) interface{} {
+			resolved, _ := ctx.Lookup(fqn)
+			return resolved
+		},
+		"entry": func(fqn string) *IndexEntry {
+			return ctx.Index[fqn]
+		},
+		"parent": func(v interface{}) interface{} {
+			parent, _ := ctx.Parent(entity(v))
+			return parent
+		},
+		"files": ctx.OrderedFiles,
+		"methodsOf": func(v interface{}) []*Method {
+			if service, isService := entity(v).(*Service); isService {
+				return ctx.MethodsOf(service)
+			}
+			return nil
+		},
+		"fieldsOf": func(v interface{}) []*Field {
+			if message, isMessage := entity(v).(*Message); isMessage {
+				return ctx.FieldsOf(message)
+			}
+			return nil
+		},
+		"valuesOf": func(v interface{}) []*EnumValue {
+			if enum, isEnum := entity(v).(*Enum); isEnum {
+				return ctx.ValuesOf(enum)
+			}
+			return nil
+		},
+		"localName": ctx.localName,
+
+		// Options
+		"isDeprecated": func(v interface{}) bool {
+			deprecated, _ := OptionsOf(entity(v))["deprecated"].(bool)
+			return deprecated
+		},
+		"option": func(name string, v interface{}) interface{} {
+			return OptionsOf(entity(v))[name]
+		},
+
+		// Formatting
+		"anchor":         anchor,
+		"markdownEscape": markdownEscape,
+		"tableCell":      markdownTableCell,
+		"join":           strings.Join,
+		"split":          strings.Split,
+		"replace":        strings.ReplaceAll,
+		"lower":          strings.ToLower,
+		"upper":          strings.ToUpper,
+		"trim":           strings.TrimSpace,
+		"hasPrefix":      strings.HasPrefix,
+		"hasSuffix":      strings.HasSuffix,
+		"firstLine": func(str string) string {
+			line, _, _ := strings.Cut(strings.TrimSpace(str), "\n")
+			return line
+		},
+	}
+}
+
+// anchorChars matches runs of characters which aren't allowed in anchors generated by `anchor`
+var anchorChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// anchor converts an FQN (or any other string) to a lowercase slug, suitable for use as a link anchor
+func anchor(str string) string {
+	return strings.Trim(anchorChars.ReplaceAllString(strings.ToLower(str), "-"), "-")
+}
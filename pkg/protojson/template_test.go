@@ -0,0 +1,107 @@
+package protojson
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplates writes templates named by their file name to a new directory, and returns its path
+func writeTemplates(t *testing.T, templates map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range templates {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTemplate(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"main.tmpl": `{{ $list := resolve "com.pseudomuto.protokit.v1.List" -}}
+{{ $list.Name }} {{ (entry "com.pseudomuto.protokit.v1.List.name").Type }} {{ (parent "com.pseudomuto.protokit.v1.List.name").FullName }}
+{{ emit "list.txt" "fields" $list }}{{ emit "type.txt" "fields" "com.pseudomuto.protokit.v1.ListType" -}}
+`,
+		"partials/fields.tmpl": `{{ define "fields" }}{{ range fieldsOf . }}{{ .Name }} {{ end }}{{ range valuesOf . }}{{ .Name }} {{ end }}{{ end }}`,
+		"partials/ignored.txt": `{{ template "missing" }}`,
+	})
+	req := fixtureRequest(t, "template="+filepath.Join(dir, "main.tmpl")+",template_partials="+filepath.Join(dir, "partials")+",out=out.txt")
+
+	got := generateFixture(t, req)
+	want := map[string]string{
+		"out.txt":  "List field com.pseudomuto.protokit.v1.List\n",
+		"list.txt": "id name type created_at details ",
+		"type.txt": "REMINDERS CHECKLIST ",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got files %q", got)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("got %q in %s, want %q", got[name], name, content)
+		}
+	}
+}
+
+func TestTemplateBlankOutput(t *testing.T) {
+	// A blank output is left out, so templates can write only the files they emit
+	dir := writeTemplates(t, map[string]string{
+		"main.tmpl": `{{ range files }}{{ emit (printf "%s.txt" .Name) "name" . }}{{ end }}
+{{ define "name" }}{{ .Package }}{{ end }}
+`,
+	})
+	got := generateFixture(t, fixtureRequest(t, "template="+filepath.Join(dir, "main.tmpl")))
+	if _, found := got["output.json"]; found || len(got) != 4 {
+		t.Errorf("got files %q", got)
+	}
+	if got["todo.proto.txt"] != "com.pseudomuto.protokit.v1" {
+		t.Errorf("got %q in todo.proto.txt", got["todo.proto.txt"])
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates map[string]string
+		partials  bool
+		want      string
+	}{
+		{
+			name:      "syntax",
+			templates: map[string]string{"main.tmpl": `{{ if }}`},
+			want:      "failed to parse template",
+		},
+		{
+			name:      "partial syntax",
+			templates: map[string]string{"main.tmpl": ``, "partials/bad.tmpl": `{{ end }}`},
+			partials:  true,
+			want:      "failed to parse template partials",
+		},
+		{
+			name:      "missing partial",
+			templates: map[string]string{"main.tmpl": `{{ template "missing" }}`},
+			want:      "failed to execute template",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTemplates(t, test.templates)
+			parameter := "template=" + filepath.Join(dir, "main.tmpl")
+			if test.partials {
+				parameter += ",template_partials=" + filepath.Join(dir, "partials")
+			}
+
+			_, err := Generate(fixtureRequest(t, parameter), nil)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
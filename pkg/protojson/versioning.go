@@ -0,0 +1,314 @@
+package protojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// Version is the version of the plugin. Release builds set it with
+// `-ldflags "-X github.com/trinsic-id/protoc-gen-json/pkg/protojson.Version=..."`; otherwise it falls back to the
+// module version recorded by `go install`.
+var Version = ""
+
+// OutputSchemaVersion is the version of the output format written by this build of the plugin.
+//
+// The version is `MAJOR.MINOR`. Adding keys bumps the minor version; removing, renaming or changing the meaning of
+// keys bumps the major version. See "Versioning" in OUTPUT.md for the full compatibility policy.
+const OutputSchemaVersion = "1.6"
+
+// outputSchemaChanges lists every version of the output format, oldest first, with the keys added in each.
+// Older versions are emitted by removing every key added after them (see `downgradeDocument`).
+var outputSchemaChanges = []struct {
+	Version string
+	// Added maps each collection to the keys added to its objects; the top-level document is the "" collection
+	Added map[string][]string
+}{
+	{Version: "1.0"},
+	{
+		Version: "1.1",
+		Added: map[string][]string{
+			"":            {"meta"},
+			"files":       {"declaration_index"},
+			"services":    {"declaration_index"},
+			"methods":     {"declaration_index", "http_rules"},
+			"messages":    {"declaration_index"},
+			"fields":      {"declaration_index", "json_name", "number", "oneof"},
+			"enums":       {"declaration_index"},
+			"enum_values": {"declaration_index"},
+		},
+	},
+	{
+		Version: "1.2",
+		Added: map[string][]string{
+			"methods":  {"client_streaming", "server_streaming"},
+			"messages": {"reserved_ranges", "reserved_names"},
+			"enums":    {"reserved_ranges", "reserved_names"},
+		},
+	},
+	{
+		Version: "1.3",
+		Added: map[string][]string{
+			"messages": {"is_recursive", "scc"},
+			"fields":   {"creates_cycle"},
+		},
+	},
+	{
+		Version: "1.4",
+		Added: map[string][]string{
+			"messages": {"example"},
+		},
+	},
+	{
+		Version: "1.5",
+		Added: map[string][]string{
+			"fields": {"well_known_type"},
+		},
+	},
+	{
+		Version: "1.6",
+		Added: map[string][]string{
+			"index": {"external", "description"},
+		},
+	},
+}
+
+// Meta describes how the output was generated, and which version of the output format it uses
+type Meta struct {
+	SchemaVersion   string   `json:"schema_version"`
+	PluginVersion   string   `json:"plugin_version"`
+	CompilerVersion string   `json:"compiler_version,omitempty"`
+	FilesToGenerate []string `json:"files_to_generate"`
+	Parameter       string   `json:"parameter"`
+}
+
+// NewMeta builds the metadata header for a request
+func NewMeta(req *plugin_go.CodeGeneratorRequest) *Meta {
+	meta := &Meta{
+		SchemaVersion:   OutputSchemaVersion,
+		PluginVersion:   pluginVersion(),
+		FilesToGenerate: append([]string{}, req.GetFileToGenerate()...),
+		Parameter:       req.GetParameter(),
+	}
+
+	if version := req.GetCompilerVersion(); version != nil {
+		meta.CompilerVersion = fmt.Sprintf("%d.%d.%d", version.GetMajor(), version.GetMinor(), version.GetPatch())
+		if len(version.GetSuffix()) > 0 {
+			meta.CompilerVersion += "-" + version.GetSuffix()
+		}
+	}
+
+	return meta
+}
+
+// pluginVersion returns the version of the plugin, or "devel" if it isn't known
+func pluginVersion() string {
+	if len(Version) > 0 {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && len(info.Main.Version) > 0 && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// validSchemaVersion reports whether `version` is a version of the output format which can be emitted
+func validSchemaVersion(version string) bool {
+	for _, change := range outputSchemaChanges {
+		if change.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaVersionBefore reports whether the output format `version` is older than `other`
+func schemaVersionBefore(version string, other string) bool {
+	for _, change := range outputSchemaChanges {
+		switch change.Version {
+		case other:
+			return false
+		case version:
+			return true
+		}
+	}
+	return false
+}
+
+// outputDocument returns the document to encode for a context: the context in the collection layout `layout`,
+// downgraded to the output format `version` if that isn't the current version
+func outputDocument(ctx *Context, layout string, version string) (interface{}, error) {
+	document := orderedContext(ctx, layout)
+	if version == OutputSchemaVersion {
+		return document, nil
+	}
+
+	// The keys are removed from a generic copy of the document, so the structs only ever describe the current version
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := decodeOrderedJSON(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	root, isObject := generic.(*orderedMap)
+	if !isObject {
+		return nil, fmt.Errorf("output document is not an object")
+	}
+	downgradeDocument(root, version)
+
+	// Older documents still describe their own version, if they have a header at all
+	if meta, found := root.Get("meta"); found {
+		meta.(*orderedMap).Set("schema_version", version)
+	}
+
+	return root, nil
+}
+
+// downgradeDocument removes every key added to the output format after `version` from a generic document
+func downgradeDocument(root *orderedMap, version string) {
+	for collection, keys := range keysAddedAfter(version) {
+		if len(collection) == 0 {
+			root.Delete(keys...)
+			continue
+		}
+
+		objects, _ := root.Get(collection)
+		for _, object := range collectionObjects(objects) {
+			object.Delete(keys...)
+		}
+	}
+}
+
+// keysAddedAfter returns the keys added to each collection of the output format after `version`, with the top-level
+// document as the "" collection (see `outputSchemaChanges`)
+func keysAddedAfter(version string) map[string][]string {
+	ret := make(map[string][]string)
+	newer := false
+	for _, change := range outputSchemaChanges {
+		if newer {
+			for collection, keys := range change.Added {
+				ret[collection] = append(ret[collection], keys...)
+			}
+		}
+		if change.Version == version {
+			newer = true
+		}
+	}
+	return ret
+}
+
+// downgradeObject returns a generic copy of an object of a collection, without the keys in `removed`
+func downgradeObject(object interface{}, removed []string) (interface{}, error) {
+	if len(removed) == 0 {
+		return object, nil
+	}
+
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := decodeOrderedJSON(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if copied, isObject := generic.(*orderedMap); isObject {
+		copied.Delete(removed...)
+	}
+	return generic, nil
+}
+
+// collectionObjects returns the objects of a generic collection, which is an object in the sorted and declared
+// layouts, and an array in the array layout
+func collectionObjects(collection interface{}) []*orderedMap {
+	ret := make([]*orderedMap, 0)
+
+	switch objects := collection.(type) {
+	case *orderedMap:
+		for _, key := range objects.Keys() {
+			value, _ := objects.Get(key)
+			if object, isObject := value.(*orderedMap); isObject {
+				ret = append(ret, object)
+			}
+		}
+	case []interface{}:
+		for _, value := range objects {
+			if object, isObject := value.(*orderedMap); isObject {
+				ret = append(ret, object)
+			}
+		}
+	}
+
+	return ret
+}
+
+// decodeOrderedJSON decodes a JSON document into orderedMaps, slices and scalars, preserving the order of keys.
+// Numbers are decoded as `json.Number`, so they are re-encoded exactly as they were.
+func decodeOrderedJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeOrderedValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	return value, nil
+}
+
+// decodeOrderedValue decodes the next value from `decoder` (see `decodeOrderedJSON`)
+func decodeOrderedValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := newOrderedMap()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.(string), value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := decoder.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
+
+// supportedSchemaVersions returns the versions of the output format which can be emitted, for error messages
+func supportedSchemaVersions() string {
+	versions := make([]string, 0, len(outputSchemaChanges))
+	for _, change := range outputSchemaChanges {
+		versions = append(versions, change.Version)
+	}
+	return strings.Join(versions, ", ")
+}
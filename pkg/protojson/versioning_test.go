@@ -0,0 +1,124 @@
+package protojson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersionDowngrade(t *testing.T) {
+	for _, layout := range []string{"sorted", "array"} {
+		for i, change := range outputSchemaChanges {
+			version := change.Version
+			t.Run(layout+" "+version, func(t *testing.T) {
+				req := fixtureRequest(t, "collections="+layout+",examples=true,schema_version="+version)
+				var document map[string]interface{}
+				if err := json.Unmarshal([]byte(generateFixture(t, req)["output.json"]), &document); err != nil {
+					t.Fatal(err)
+				}
+
+				// Every key added after the version is gone, from every object of its collection
+				for _, newer := range outputSchemaChanges[i+1:] {
+					for collection, keys := range newer.Added {
+						objects := []interface{}{document}
+						if len(collection) > 0 {
+							objects = versionTestObjects(document[collection])
+						}
+						for _, object := range objects {
+							for _, key := range keys {
+								if _, found := object.(map[string]interface{})[key]; found {
+									t.Errorf("%s %q (added in %s) is in the output", collection, key, newer.Version)
+								}
+							}
+						}
+					}
+				}
+
+				// The header describes the version written, if the version has one
+				meta, found := document["meta"].(map[string]interface{})
+				if version == "1.0" {
+					if found {
+						t.Errorf("version 1.0 has a meta header")
+					}
+					return
+				}
+				if !found {
+					t.Fatalf("version %s has no meta header", version)
+				}
+				if got := meta["schema_version"]; got != version {
+					t.Errorf("got schema_version %v, want %s", got, version)
+				}
+			})
+		}
+	}
+
+	// Keys of the current version aren't removed
+	req := fixtureRequest(t, "collections=array")
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(generateFixture(t, req)["output.json"]), &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, object := range versionTestObjects(document["fields"]) {
+		if _, found := object.(map[string]interface{})["number"]; !found {
+			t.Fatalf("field %v has no number in the current version", object)
+		}
+	}
+}
+
+func TestSchemaVersionDowngradeNDJSON(t *testing.T) {
+	for i, change := range outputSchemaChanges {
+		version := change.Version
+		t.Run(version, func(t *testing.T) {
+			req := fixtureRequest(t, "format=ndjson,examples=true,schema_version="+version)
+			output := generateFixture(t, req)["output.json"]
+
+			var meta map[string]interface{}
+			for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+				var record map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatal(err)
+				}
+				if record["kind"] == "meta" {
+					meta = record
+					continue
+				}
+
+				// Records are named by kind, and their collections by the plural
+				collection := record["kind"].(string) + "s"
+				for _, newer := range outputSchemaChanges[i+1:] {
+					for _, key := range newer.Added[collection] {
+						if _, found := record[key]; found {
+							t.Errorf("%s %q (added in %s) is in the output", record["fqn"], key, newer.Version)
+						}
+					}
+				}
+			}
+
+			if version == "1.0" {
+				if meta != nil {
+					t.Errorf("version 1.0 has a meta record")
+				}
+				return
+			}
+			if meta == nil {
+				t.Fatalf("version %s has no meta record", version)
+			}
+			if got := meta["schema_version"]; got != version {
+				t.Errorf("got schema_version %v, want %s", got, version)
+			}
+		})
+	}
+}
+
+// versionTestObjects returns the objects of a decoded collection, which is an object in the sorted layout and an array
+// in the array layout
+func versionTestObjects(collection interface{}) []interface{} {
+	if objects, isArray := collection.([]interface{}); isArray {
+		return objects
+	}
+	ret := make([]interface{}, 0)
+	for _, object := range collection.(map[string]interface{}) {
+		ret = append(ret, object)
+	}
+	return ret
+}
@@ -0,0 +1,128 @@
+package protojson
+
+import "strings"
+
+// wellKnownDocsURL is the reference documentation of the well-known types; each type has an anchor of its lowercased
+// name, EG `#timestamp`
+const wellKnownDocsURL = "https://protobuf.dev/reference/protobuf/google.protobuf/"
+
+// WellKnownType describes a well-known type which has a special representation in the proto3 JSON mapping. The
+// well-known types are usually not among the generated files, so this describes them in place of their declarations.
+type WellKnownType struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// JSON describes how the type is represented in proto3 JSON, EG `string (RFC 3339 timestamp)`
+	JSON string `json:"json"`
+	// URL is the reference documentation of the type
+	URL string `json:"url"`
+
+	// schema returns the JSON Schema of the type
+	schema func() *orderedMap
+	// example returns an example value of the type in proto3 JSON
+	example func() interface{}
+}
+
+// wellKnownTypes are the well-known types with a special representation in the proto3 JSON mapping, by FQN
+var wellKnownTypes = make(map[string]*WellKnownType)
+
+func init() {
+	add := func(name string, description string, json string, schema func() *orderedMap, example func() interface{}) {
+		fqn := "google.protobuf." + name
+		wellKnownTypes[fqn] = &WellKnownType{
+			Name:        fqn,
+			Description: description,
+			JSON:        json,
+			URL:         wellKnownDocsURL + "#" + strings.ToLower(name),
+			schema:      schema,
+			example:     example,
+		}
+	}
+
+	add("Timestamp",
+		"A point in time, independent of any time zone, with nanosecond precision.",
+		`string (RFC 3339 timestamp in UTC, EG "1972-01-01T10:00:20.021Z")`,
+		func() *orderedMap { return schemaOf("type", "string", "format", "date-time") },
+		func() interface{} { return "1970-01-01T00:00:00Z" })
+	add("Duration",
+		"A signed, fixed-length span of time, with nanosecond precision.",
+		`string (seconds with up to 9 fractional digits and an "s" suffix, EG "1.5s")`,
+		func() *orderedMap { return schemaOf("type", "string", "pattern", `^-?[0-9]+(\.[0-9]{1,9})?s$`) },
+		func() interface{} { return "1s" })
+	add("FieldMask",
+		"A set of field paths, EG the fields to return or update.",
+		`string (comma-separated lowerCamelCase paths, EG "user.displayName,photo")`,
+		func() *orderedMap { return schemaOf("type", "string") },
+		func() interface{} { return "path.to.field" })
+	add("Struct",
+		"A structured data value with dynamically typed fields, like a JSON object.",
+		"object (any JSON object)",
+		func() *orderedMap { return schemaOf("type", "object") },
+		func() interface{} { return newOrderedMap() })
+	add("Value",
+		"A dynamically typed value: null, a number, a string, a boolean, a Struct or a ListValue.",
+		"any JSON value",
+		func() *orderedMap { return schemaOf() },
+		func() interface{} { return nil })
+	add("ListValue",
+		"A list of dynamically typed values, like a JSON array.",
+		"array (any JSON array)",
+		func() *orderedMap { return schemaOf("type", "array") },
+		func() interface{} { return []interface{}{} })
+	add("NullValue",
+		"The null value of a Value.",
+		"null",
+		func() *orderedMap { return schemaOf("type", "null") },
+		func() interface{} { return nil })
+	add("Empty",
+		"An empty message, used as the request or response of methods which don't need one.",
+		"object (always {})",
+		func() *orderedMap { return schemaOf("type", "object", "maxProperties", 0) },
+		func() interface{} { return newOrderedMap() })
+	add("Any",
+		"An arbitrary message, with a URL which identifies its type.",
+		`object (the message's fields, or "value" for well-known types, with an "@type" URL)`,
+		func() *orderedMap {
+			return schemaOf(
+				"type", "object",
+				"properties", schemaOf("@type", schemaOf("type", "string")),
+				"required", []string{"@type"},
+			)
+		},
+		func() interface{} { return schemaOf("@type", "type.googleapis.com/google.protobuf.Empty") })
+
+	// The wrappers are represented as the value they wrap, or null
+	wrappers := []struct{ name, scalar, json string }{
+		{"DoubleValue", "double", "number"},
+		{"FloatValue", "float", "number"},
+		{"Int64Value", "int64", "string (decimal integer)"},
+		{"UInt64Value", "uint64", "string (decimal integer)"},
+		{"Int32Value", "int32", "number (integer)"},
+		{"UInt32Value", "uint32", "number (integer)"},
+		{"BoolValue", "bool", "boolean"},
+		{"StringValue", "string", "string"},
+		{"BytesValue", "bytes", "string (base64)"},
+	}
+	for _, wrapper := range wrappers {
+		scalar := wrapper.scalar
+		add(wrapper.name,
+			"A nullable "+scalar+", wrapped so that its presence can be detected.",
+			wrapper.json+" or null",
+			func() *orderedMap {
+				return schemaOf("anyOf", []interface{}{primitiveSchema(scalar), schemaOf("type", "null")})
+			},
+			func() interface{} { return scalarExample(scalar, "") })
+	}
+}
+
+// markWellKnownTypes sets `WellKnownType` on every field whose type is a well-known type. Map fields whose values
+// are a well-known type have it too, as do the value fields of their map entries.
+func markWellKnownTypes(ctx *Context) {
+	for _, field := range ctx.Fields {
+		field.WellKnownType = wellKnownTypes[field.FullType]
+		if entry, found := ctx.Messages[field.FullType]; found && entry.IsMapEntry && len(entry.Fields) == 2 {
+			if value, found := ctx.Fields[entry.Fields[1]]; found {
+				field.WellKnownType = wellKnownTypes[value.FullType]
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package protojson
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestMarkWellKnownTypes(t *testing.T) {
+	req := fixtureRequest(t, "")
+	// message Schedule { map<string, google.protobuf.Timestamp> times = 1; map<string, string> notes = 2; }
+	entry := func(name string, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		value.Name, value.JsonName, value.Number = proto.String("value"), proto.String("value"), proto.Int32(2)
+		value.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name: proto.String("key"), JsonName: proto.String("key"), Number: proto.Int32(1),
+				Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:  descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}, value},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+	mapField := func(name string, number int32, entry string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".com.pseudomuto.protokit.v1.Schedule." + entry),
+		}
+	}
+	addTestMessages(t, req, &descriptorpb.DescriptorProto{
+		Name:  proto.String("Schedule"),
+		Field: []*descriptorpb.FieldDescriptorProto{mapField("times", 1, "TimesEntry"), mapField("notes", 2, "NotesEntry")},
+		NestedType: []*descriptorpb.DescriptorProto{
+			entry("TimesEntry", &descriptorpb.FieldDescriptorProto{
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.Timestamp"),
+			}),
+			entry("NotesEntry", &descriptorpb.FieldDescriptorProto{Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()}),
+		},
+	})
+
+	ctx := buildFixture(t, req)
+
+	tests := []struct {
+		fqn  string
+		want string
+	}{
+		{"com.pseudomuto.protokit.v1.List.created_at", "google.protobuf.Timestamp"},
+		{"com.pseudomuto.protokit.v1.List.details", "google.protobuf.Any"},
+		{"com.pseudomuto.protokit.v1.Schedule.times", "google.protobuf.Timestamp"},
+		{"com.pseudomuto.protokit.v1.Schedule.TimesEntry.value", "google.protobuf.Timestamp"},
+		{"com.pseudomuto.protokit.v1.Schedule.notes", ""},
+		{"com.pseudomuto.protokit.v1.List.name", ""},
+	}
+	for _, test := range tests {
+		field, found := ctx.Fields[test.fqn]
+		if !found {
+			t.Errorf("%s is not a field", test.fqn)
+			continue
+		}
+		got := ""
+		if field.WellKnownType != nil {
+			got = field.WellKnownType.Name
+		}
+		if got != test.want {
+			t.Errorf("%s has the well-known type %q, want %q", test.fqn, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package protojson
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlEncoder writes the context as a single YAML document.
+//
+// The document has exactly the same structure as the JSON output, including key order, but multi-line strings
+// (such as descriptions) are written as literal block scalars so they remain readable in diffs.
+type yamlEncoder struct {
+	indent  int
+	layout  string
+	version string
+}
+
+func (e *yamlEncoder) Encode(w io.Writer, ctx *Context) error {
+	// Round-trip through JSON, so the `json` struct tags and the collection layout are honoured.
+	// JSON is valid YAML, so this yields a node tree with the keys in the same order as the JSON output.
+	document, err := outputDocument(ctx, e.layout, e.version)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	root := new(yaml.Node)
+	if err := yaml.Unmarshal(encoded, root); err != nil {
+		return err
+	}
+	restyleYAML(root)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(e.indent)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// restyleYAML converts the JSON-flavoured styles of a parsed node tree to block style
+func restyleYAML(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style = 0
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+			node.Style = yaml.LiteralStyle
+		} else {
+			// The encoder will still quote strings which would otherwise be read back as another type
+			node.Style = 0
+		}
+	}
+
+	for _, child := range node.Content {
+		restyleYAML(child)
+	}
+}
+
+// yamlIndent converts an indentation string to a number of spaces, since YAML can't be indented with tabs
+func yamlIndent(indent string) int {
+	width := len(strings.ReplaceAll(indent, "\t", "    "))
+	if width == 0 {
+		return 2
+	}
+	return width
+}